@@ -0,0 +1,189 @@
+// Package arlongtest checks in tests that a handler behaves the way its
+// Swagger document says it does.
+package arlongtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Contract calls every operation of a document on a handler and checks the
+// responses.
+type Contract struct {
+	Handler http.Handler
+	Swagger *schema.Swagger
+
+	// Prepare, when set, is called on every request before it is sent,
+	// e.g. to add credentials.
+	Prepare func(r *http.Request)
+
+	// AllowErrors accepts declared 4xx and 5xx responses. Without it
+	// every operation must succeed with a 2xx.
+	AllowErrors bool
+
+	validator *middleware.Validator
+}
+
+// Check runs a contract test of handler against swagger.
+func Check(t *testing.T, handler http.Handler, swagger *schema.Swagger) {
+	(&Contract{Handler: handler, Swagger: swagger}).Run(t)
+}
+
+// Run calls each operation in a subtest named after its method and path.
+// Requests are built from the examples of the parameters, or values
+// synthesized from their types. A subtest fails when the status code is
+// not a declared 2xx or the body does not match the declared schema.
+func (c *Contract) Run(t *testing.T) {
+	c.validator = middleware.New(c.Swagger)
+	for _, rt := range c.validator.Router().Routes() {
+		rt := rt
+		t.Run(rt.Method+" "+rt.Template, func(t *testing.T) {
+			for _, problem := range c.check(rt) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func (c *Contract) check(rt *middleware.Route) []string {
+	req, err := c.request(rt)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if c.Prepare != nil {
+		c.Prepare(req)
+	}
+
+	w := httptest.NewRecorder()
+	c.Handler.ServeHTTP(w, req)
+
+	problems := []string{}
+	if !c.AllowErrors && (w.Code < 200 || w.Code > 299) {
+		problems = append(problems, fmt.Sprintf("%d is not a success: %s", w.Code, strings.TrimSpace(w.Body.String())))
+	}
+	for _, err := range c.validator.CheckResponse(rt, w.Code, w.Header(), w.Body.Bytes()) {
+		problems = append(problems, fmt.Sprintf("%d %s", w.Code, err))
+	}
+
+	return problems
+}
+
+// request builds a request for an operation with every parameter set
+// that a value can be built for. It fails when a required parameter has
+// none, e.g. a file.
+func (c *Contract) request(rt *middleware.Route) (*http.Request, error) {
+	route := c.Swagger.BasePath + rt.Template
+	query, form, header := url.Values{}, url.Values{}, http.Header{}
+	var body []byte
+
+	for _, param := range c.validator.Parameters(rt) {
+		if param.In == "body" {
+			b, err := json.Marshal(param.Synthesize(c.Swagger.Definitions))
+			if err != nil {
+				return nil, err
+			}
+			body = b
+			continue
+		}
+
+		values := c.paramValues(param)
+		if values == nil {
+			if param.Required {
+				return nil, fmt.Errorf("cannot build a value for the required %s parameter %s of type %s, give it an example", param.In, param.Name, param.Type)
+			}
+			continue
+		}
+
+		switch param.In {
+		case schema.PATH:
+			route = strings.Replace(route, "{"+param.Name+"}", url.PathEscape(values[0]), -1)
+		case schema.QUERY:
+			query[param.Name] = values
+		case schema.HEADER:
+			header.Set(param.Name, values[0])
+		case schema.FORMDATA:
+			form[param.Name] = values
+		}
+	}
+
+	if len(query) > 0 {
+		route += "?" + query.Encode()
+	}
+
+	req := httptest.NewRequest(rt.Method, route, nil)
+	switch {
+	case body != nil:
+		req = httptest.NewRequest(rt.Method, route, bytes.NewReader(body))
+		req.Header.Set("Content-Type", schema.MIME_JSON)
+	case len(form) > 0 && c.consumes(rt, schema.MIME_MULTIPART):
+		b := &bytes.Buffer{}
+		mw := multipart.NewWriter(b)
+		for name, values := range form {
+			for _, val := range values {
+				mw.WriteField(name, val)
+			}
+		}
+		mw.Close()
+		req = httptest.NewRequest(rt.Method, route, b)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+	case len(form) > 0:
+		req = httptest.NewRequest(rt.Method, route, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", schema.MIME_FORM)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	return req, nil
+}
+
+// consumes reports whether an operation, or the document when the
+// operation declares nothing, consumes mime.
+func (c *Contract) consumes(rt *middleware.Route, mime string) bool {
+	consumes := rt.Operation.Consumes
+	if len(consumes) == 0 {
+		consumes = c.Swagger.Consumes
+	}
+
+	for _, m := range consumes {
+		if m == mime {
+			return true
+		}
+	}
+
+	return false
+}
+
+// paramValues returns the values sent for a non-body parameter, or nil
+// when none can be built. Arrays are joined by their collectionFormat.
+func (c *Contract) paramValues(param *schema.Parameter) []string {
+	val := param.Synthesize(c.Swagger.Definitions)
+	if val == nil {
+		return nil
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return []string{fmt.Sprint(val)}
+	}
+
+	values := []string{}
+	for _, item := range items {
+		values = append(values, fmt.Sprint(item))
+	}
+	if sep := param.Separator(); sep != "" {
+		return []string{strings.Join(values, sep)}
+	}
+
+	return values
+}
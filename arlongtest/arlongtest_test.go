@@ -0,0 +1,135 @@
+package arlongtest
+
+import (
+	"encoding/json"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/mock"
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func testSwagger() *schema.Swagger {
+	swagger := schema.New()
+	swagger.Definitions["Pet"] = &schema.Schema{
+		Type:     "object",
+		Required: []string{"id", "name"},
+		Properties: map[string]*schema.Schema{
+			"id":   {Type: "integer"},
+			"name": {Type: "string"},
+		},
+	}
+	swagger.Paths["/pets/{id}"] = &schema.Path{
+		GET: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "id", In: schema.PATH, Type: "integer", Required: true, Minimum: 5},
+				{Name: "X-Tenant", In: schema.HEADER, Type: "string", Required: true, Example: "acme"},
+			},
+			Responses: map[string]*schema.Responses{
+				"200": {Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+		},
+	}
+	swagger.Paths["/pets"] = &schema.Path{
+		POST: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "body", In: "body", Required: true, Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"201": {Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+		},
+	}
+
+	return swagger
+}
+
+func TestCheckMock(t *testing.T) {
+	Check(t, mock.New(testSwagger()), testSwagger())
+}
+
+func TestCheckDrift(t *testing.T) {
+	var tenant, path string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, path = r.Header.Get("X-Tenant"), r.URL.Path
+		w.Header().Set("Content-Type", schema.MIME_JSON)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "7"})
+	})
+
+	c := &Contract{Handler: handler, Swagger: testSwagger()}
+	c.validator = middleware.New(c.Swagger)
+
+	problems := c.check(c.validator.Router().Routes()[1])
+	if tenant != "acme" || path != "/pets/5" {
+		t.Errorf("unexpected request %s %s", path, tenant)
+	}
+	if len(problems) != 2 || !strings.Contains(problems[0], "name") || !strings.Contains(problems[1], "/id") {
+		t.Errorf("unexpected problems %v", problems)
+	}
+
+	problems = c.check(c.validator.Router().Routes()[0])
+	if len(problems) != 1 || !strings.Contains(problems[0], "200 is not declared") {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	swagger := schema.New()
+	swagger.Paths["/upload"] = &schema.Path{
+		POST: &schema.Operation{
+			Consumes: []string{schema.MIME_MULTIPART},
+			Parameters: []*schema.Parameter{
+				{Name: "note", In: schema.FORMDATA, Type: "string", Example: "hi"},
+				{Name: "tags", In: schema.QUERY, Type: "array", CollectionFormat: "multi", MinItems: 2, Items: &schema.Items{Type: "string"}},
+				{Name: "file", In: schema.FORMDATA, Type: "file"},
+			},
+		},
+		PUT: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "file", In: schema.FORMDATA, Type: "file", Required: true},
+			},
+		},
+	}
+
+	c := &Contract{Swagger: swagger}
+	c.validator = middleware.New(swagger)
+	routes := c.validator.Router().Routes()
+	if routes[1].Method != "PUT" {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+
+	req, err := c.request(routes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("expected a multipart form: %s", err)
+	}
+	if req.FormValue("note") != "hi" || len(req.URL.Query()["tags"]) != 2 || req.MultipartForm.Value["file"] != nil {
+		t.Errorf("unexpected request %s %v", req.URL, req.MultipartForm.Value)
+	}
+
+	if _, err := c.request(routes[1]); err == nil || !strings.Contains(err.Error(), "required formData parameter file") {
+		t.Errorf("expected the required file to fail, got %v", err)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	swagger := testSwagger()
+	swagger.Paths["/pets"].POST.Responses["409"] = &schema.Responses{Description: "Conflict"}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	c := &Contract{Handler: handler, Swagger: swagger}
+	c.validator = middleware.New(swagger)
+	if problems := c.check(c.validator.Router().Routes()[0]); len(problems) != 1 || !strings.Contains(problems[0], "409 is not a success") {
+		t.Errorf("unexpected problems %v", problems)
+	}
+
+	c.AllowErrors = true
+	if problems := c.check(c.validator.Router().Routes()[0]); len(problems) != 0 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
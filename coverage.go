@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/middleware"
+	"os"
+)
+
+var coverageCommand = cli.Command{
+	Name:  "coverage",
+	Usage: "Compare requests recorded during tests with the operations of the spec",
+	Flags: append([]cli.Flag{
+		specFlag,
+
+		cli.StringFlag{
+			Name:  "record, r",
+			Value: "arlong.record",
+			Usage: "File written by middleware.Recording",
+		},
+
+		cli.IntFlag{
+			Name:  "min",
+			Value: 0,
+			Usage: "Exit with 1 when less than this percentage of operations was exercised",
+		},
+	}, parserFlags...),
+	Action: func(c *cli.Context) {
+		swagger, err := loadSwagger(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		hits, err := middleware.ReadHits(c.String("record"))
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		coverage := middleware.NewCoverage(swagger, hits)
+		fmt.Println(coverage)
+		printSection("undocumented", coverage.Undocumented)
+		printSection("unexercised", coverage.Unexercised)
+		printSection("unseen responses", coverage.Unseen)
+
+		if coverage.OperationPercent() < float64(c.Int("min")) {
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/docs"
+	"io/ioutil"
+	"os"
+)
+
+var docsCommand = cli.Command{
+	Name:  "docs",
+	Usage: "Render the spec as a Markdown or HTML API reference",
+	Flags: append([]cli.Flag{
+		specFlag,
+
+		cli.StringFlag{
+			Name:  "format",
+			Value: "md",
+			Usage: "Output format (md, html)",
+		},
+
+		cli.StringFlag{
+			Name:  "out, o",
+			Value: "",
+			Usage: "Output file, stdout when empty",
+		},
+	}, parserFlags...),
+	Action: func(c *cli.Context) {
+		swagger, err := loadSwagger(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		var b []byte
+		switch c.String("format") {
+		case "md":
+			b, err = docs.Markdown(swagger)
+		case "html":
+			b, err = docs.HTML(swagger)
+		default:
+			err = fmt.Errorf("Unknown docs format %s", c.String("format"))
+		}
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		if out := c.String("out"); out != "" {
+			err = ioutil.WriteFile(out, b, 0644)
+		} else {
+			_, err = os.Stdout.Write(b)
+		}
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+		}
+	},
+}
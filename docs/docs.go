@@ -0,0 +1,495 @@
+// Package docs renders a Swagger document as a self-contained Markdown or
+// HTML API reference.
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type page struct {
+	Title       string
+	Version     string
+	Description string
+	BaseURL     string
+	Security    []security
+	Sections    []section
+	Definitions []definition
+}
+
+type security struct {
+	Name        string
+	Type        string
+	Description string
+	Details     string
+	Scopes      []string
+}
+
+type section struct {
+	Name        string
+	Description string
+	Operations  []*operation
+}
+
+type operation struct {
+	Method      string
+	Path        string
+	Anchor      string
+	Summary     string
+	Description string
+	OperationId string
+	Deprecated  bool
+	Params      []param
+	Responses   []response
+	Security    []string
+	Curl        string
+}
+
+type param struct {
+	Name        string
+	In          string
+	Type        typeRef
+	Required    bool
+	Description string
+	Enum        string
+}
+
+type response struct {
+	Code        string
+	Description string
+	Type        typeRef
+}
+
+type definition struct {
+	Name        string
+	Anchor      string
+	Description string
+	Type        typeRef
+	Properties  []property
+}
+
+type property struct {
+	Name        string
+	Type        typeRef
+	Required    bool
+	Description string
+	Enum        string
+}
+
+// typeRef is the displayed type of a value. Anchor links to the definition
+// it refers to, if any.
+type typeRef struct {
+	Text   string
+	Anchor string
+}
+
+// anchor returns an HTML id / Markdown fragment for s.
+func anchor(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, s)
+	for strings.Contains(s, "--") {
+		s = strings.Replace(s, "--", "-", -1)
+	}
+
+	return strings.Trim(s, "-")
+}
+
+func definitionName(ref string) string {
+	return strings.TrimPrefix(ref, "#/definitions/")
+}
+
+func schemaType(s *schema.Schema) typeRef {
+	if s == nil {
+		return typeRef{}
+	}
+
+	switch {
+	case s.Ref != "":
+		name := definitionName(s.Ref)
+		return typeRef{Text: name, Anchor: "definition-" + anchor(name)}
+	case s.Type == "array":
+		item := schemaType(s.Items)
+		return typeRef{Text: "[]" + item.Text, Anchor: item.Anchor}
+	case s.AdditionalProperties != nil:
+		value := schemaType(s.AdditionalProperties)
+		return typeRef{Text: "map[string]" + value.Text, Anchor: value.Anchor}
+	case len(s.AllOf) > 0:
+		names := []string{}
+		for _, sub := range s.AllOf {
+			names = append(names, schemaType(sub).Text)
+		}
+		return typeRef{Text: "allOf(" + strings.Join(names, ", ") + ")"}
+	}
+
+	return basicType(s.Type, s.Format)
+}
+
+func basicType(typ, format string) typeRef {
+	if typ == "" {
+		typ = "object"
+	}
+	if format != "" {
+		typ += "(" + format + ")"
+	}
+
+	return typeRef{Text: typ}
+}
+
+func paramType(p *schema.Parameter) typeRef {
+	if p.Schema != nil {
+		return schemaType(p.Schema)
+	}
+	if p.Type == "array" && p.Items != nil {
+		return typeRef{Text: "[]" + basicType(p.Items.Type, p.Items.Format).Text}
+	}
+
+	return basicType(p.Type, p.Format)
+}
+
+// build collects what both renderers show.
+func build(swagger *schema.Swagger) *page {
+	p := &page{
+		Title:       swagger.Info.Title,
+		Version:     swagger.Info.Version,
+		Description: swagger.Info.Description,
+		BaseURL:     baseURL(swagger),
+	}
+	if p.Title == "" {
+		p.Title = "API Reference"
+	}
+
+	p.Security = buildSecurity(swagger)
+	p.Sections = buildSections(swagger, p.BaseURL)
+	p.Definitions = buildDefinitions(swagger)
+
+	return p
+}
+
+func baseURL(swagger *schema.Swagger) string {
+	scheme := "https"
+	if len(swagger.Schemes) > 0 {
+		scheme = swagger.Schemes[0]
+	}
+	host := swagger.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	return scheme + "://" + host + strings.TrimSuffix(swagger.BasePath, "/")
+}
+
+func buildSecurity(swagger *schema.Swagger) []security {
+	names := make([]string, 0, len(swagger.SecurityDefinitions))
+	for name := range swagger.SecurityDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []security{}
+	for _, name := range names {
+		def := swagger.SecurityDefinitions[name]
+		sec := security{Name: name, Type: def.Type, Description: def.Description}
+		switch def.Type {
+		case "apiKey":
+			sec.Details = fmt.Sprintf("%s %s", def.In, def.Name)
+		case "oauth2":
+			details := []string{def.Flow}
+			if def.AuthorizationUrl != "" {
+				details = append(details, def.AuthorizationUrl)
+			}
+			if def.TokenUrl != "" {
+				details = append(details, def.TokenUrl)
+			}
+			sec.Details = strings.Join(details, " ")
+		}
+		for scope, desc := range def.Scopes {
+			sec.Scopes = append(sec.Scopes, scope+": "+desc)
+		}
+		sort.Strings(sec.Scopes)
+		list = append(list, sec)
+	}
+
+	return list
+}
+
+// buildSections groups operations by tag, in the order of the document's
+// tags first. An operation with several tags is listed under each one.
+func buildSections(swagger *schema.Swagger, base string) []section {
+	validator := middleware.New(swagger)
+	byTag := make(map[string][]*operation)
+	for _, rt := range validator.Router().Routes() {
+		op := buildOperation(swagger, validator, rt, base)
+		tags := rt.Operation.Tags
+		if len(tags) == 0 {
+			tags = []string{"default"}
+		}
+		for _, tag := range tags {
+			byTag[tag] = append(byTag[tag], op)
+		}
+	}
+
+	sections := []section{}
+	seen := make(map[string]bool)
+	for _, tag := range swagger.Tags {
+		if ops, ok := byTag[tag.Name]; ok && !seen[tag.Name] {
+			sections = append(sections, section{Name: tag.Name, Description: tag.Description, Operations: ops})
+			seen[tag.Name] = true
+		}
+	}
+
+	names := []string{}
+	for name := range byTag {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sections = append(sections, section{Name: name, Operations: byTag[name]})
+	}
+
+	for _, sec := range sections {
+		sort.SliceStable(sec.Operations, func(i, j int) bool {
+			a, b := sec.Operations[i], sec.Operations[j]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.Method < b.Method
+		})
+	}
+
+	return sections
+}
+
+func buildOperation(swagger *schema.Swagger, v *middleware.Validator, rt *middleware.Route, base string) *operation {
+	op := &operation{
+		Method:      rt.Method,
+		Path:        rt.Template,
+		Anchor:      "operation-" + anchor(rt.Method+"-"+rt.Template),
+		Summary:     rt.Operation.Summary,
+		Description: rt.Operation.Description,
+		OperationId: rt.Operation.OperationId,
+		Deprecated:  rt.Operation.Deprecated,
+	}
+
+	params := v.Parameters(rt)
+	for _, p := range params {
+		op.Params = append(op.Params, param{
+			Name:        p.Name,
+			In:          p.In,
+			Type:        paramType(p),
+			Required:    p.Required,
+			Description: p.Description,
+			Enum:        strings.Join(p.Enum, ", "),
+		})
+	}
+
+	codes := make([]string, 0, len(rt.Operation.Responses))
+	for code := range rt.Operation.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		resp := rt.Operation.Responses[code]
+		if resp.Ref != "" {
+			if global, ok := swagger.Responses[strings.TrimPrefix(resp.Ref, "#/responses/")]; ok {
+				resp = global
+			}
+		}
+		op.Responses = append(op.Responses, response{Code: code, Description: resp.Description, Type: schemaType(resp.Schema)})
+	}
+
+	requirements := rt.Operation.Security
+	if requirements == nil {
+		requirements = swagger.Security
+	}
+	for _, requirement := range requirements {
+		names := []string{}
+		for name, scopes := range requirement {
+			if len(scopes) > 0 {
+				name += " (" + strings.Join(scopes, ", ") + ")"
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		op.Security = append(op.Security, strings.Join(names, " and "))
+	}
+
+	op.Curl = curl(swagger, rt, params, requirements, base)
+
+	return op
+}
+
+// curl returns a curl command calling an operation with example values
+// and placeholders for the credentials of its first security requirement.
+func curl(swagger *schema.Swagger, rt *middleware.Route, params []*schema.Parameter, requirements []map[string][]string, base string) string {
+	path, query := rt.Template, url.Values{}
+	args := []string{"curl", "-X", rt.Method}
+	headers, form := []string{}, []string{}
+	var body []byte
+
+	if len(requirements) > 0 {
+		names := []string{}
+		for name := range requirements[0] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			def := swagger.SecurityDefinitions[name]
+			switch {
+			case def == nil:
+			case def.Type == "basic":
+				headers = append(headers, "-u", shellQuote("<username>:<password>"))
+			case def.Type == "oauth2":
+				headers = append(headers, "-H", shellQuote("Authorization: Bearer <token>"))
+			case def.Type == "apiKey" && def.In == schema.HEADER:
+				headers = append(headers, "-H", shellQuote(def.Name+": <"+name+">"))
+			case def.Type == "apiKey" && def.In == schema.QUERY:
+				query.Set(def.Name, "<"+name+">")
+			}
+		}
+	}
+
+	value := func(p *schema.Parameter) string {
+		val := p.Synthesize(swagger.Definitions)
+		if items, ok := val.([]interface{}); ok {
+			values := []string{}
+			for _, item := range items {
+				values = append(values, fmt.Sprint(item))
+			}
+			return strings.Join(values, ",")
+		}
+		return fmt.Sprint(val)
+	}
+
+	for _, p := range params {
+		switch p.In {
+		case schema.PATH:
+			path = strings.Replace(path, "{"+p.Name+"}", url.PathEscape(value(p)), -1)
+		case schema.QUERY:
+			if p.Required || p.Example != nil {
+				query.Set(p.Name, value(p))
+			}
+		case schema.HEADER:
+			headers = append(headers, "-H", shellQuote(p.Name+": "+value(p)))
+		case schema.FORMDATA:
+			form = append(form, "-F", shellQuote(p.Name+"="+value(p)))
+		case "body":
+			body, _ = json.MarshalIndent(p.Synthesize(swagger.Definitions), "", "  ")
+		}
+	}
+
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	args = append(args, shellQuote(target))
+	args = append(args, headers...)
+	if body != nil {
+		args = append(args, "-H", shellQuote("Content-Type: "+schema.MIME_JSON), "-d", shellQuote(string(body)))
+	}
+	args = append(args, form...)
+
+	return strings.Join(args, " ")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// flatten returns the properties and required names of a definition with
+// its allOf parts merged in.
+func flatten(swagger *schema.Swagger, s *schema.Schema, seen map[string]bool) (map[string]*schema.Schema, []string) {
+	props, required := map[string]*schema.Schema{}, []string{}
+	if s == nil {
+		return props, required
+	}
+
+	if s.Ref != "" {
+		name := definitionName(s.Ref)
+		if seen[name] {
+			return props, required
+		}
+		seen[name] = true
+		return flatten(swagger, swagger.Definitions[name], seen)
+	}
+
+	for _, sub := range s.AllOf {
+		subProps, subRequired := flatten(swagger, sub, seen)
+		for name, prop := range subProps {
+			props[name] = prop
+		}
+		required = append(required, subRequired...)
+	}
+	for name, prop := range s.Properties {
+		props[name] = prop
+	}
+	required = append(required, s.Required...)
+
+	return props, required
+}
+
+func buildDefinitions(swagger *schema.Swagger) []definition {
+	names := make([]string, 0, len(swagger.Definitions))
+	for name := range swagger.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defs := []definition{}
+	for _, name := range names {
+		s := swagger.Definitions[name]
+		def := definition{
+			Name:        name,
+			Anchor:      "definition-" + anchor(name),
+			Description: strings.TrimSpace(s.Description),
+		}
+
+		props, required := flatten(swagger, s, map[string]bool{name: true})
+		if len(props) == 0 {
+			def.Type = schemaType(s)
+			if len(s.Enum) > 0 {
+				def.Type.Text += " (" + strings.Join(s.Enum, ", ") + ")"
+			}
+		}
+
+		isRequired := make(map[string]bool)
+		for _, r := range required {
+			isRequired[r] = true
+		}
+
+		propNames := make([]string, 0, len(props))
+		for propName := range props {
+			propNames = append(propNames, propName)
+		}
+		sort.Strings(propNames)
+
+		for _, propName := range propNames {
+			prop := props[propName]
+			def.Properties = append(def.Properties, property{
+				Name:        propName,
+				Type:        schemaType(prop),
+				Required:    isRequired[propName],
+				Description: strings.TrimSpace(prop.Description),
+				Enum:        strings.Join(prop.Enum, ", "),
+			})
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs
+}
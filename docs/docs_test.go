@@ -0,0 +1,112 @@
+package docs
+
+import (
+	"github.com/peak6/arlong/schema"
+	"strings"
+	"testing"
+)
+
+func testSwagger() *schema.Swagger {
+	swagger := schema.New()
+	swagger.Info = schema.Info{Title: "Pet Store", Version: "1.0"}
+	swagger.Host = "api.example.com"
+	swagger.BasePath = "/v1"
+	swagger.Tags = []*schema.Tag{{Name: "pets", Description: "Everything about pets"}}
+	swagger.SecurityDefinitions["token"] = &schema.SecurityDefinitions{Type: "apiKey", In: "header", Name: "Authorization"}
+	swagger.Security = []map[string][]string{{"token": {}}}
+	swagger.Definitions["Base"] = &schema.Schema{
+		Type:       "object",
+		Required:   []string{"id"},
+		Properties: map[string]*schema.Schema{"id": {Type: "integer", Format: "int64"}},
+	}
+	swagger.Definitions["Pet"] = &schema.Schema{
+		AllOf: []*schema.Schema{
+			{Ref: "#/definitions/Base"},
+			{
+				Type:     "object",
+				Required: []string{"name"},
+				Properties: map[string]*schema.Schema{
+					"name":   {Type: "string", Description: "Name | nickname"},
+					"status": {Type: "string", Enum: []string{"available", "sold"}},
+				},
+			},
+		},
+	}
+	swagger.Paths["/pets/{id}"] = &schema.Path{
+		GET: &schema.Operation{
+			Tags:        []string{"pets"},
+			Summary:     "Find a pet",
+			OperationId: "getPet",
+			Parameters: []*schema.Parameter{
+				{Name: "id", In: schema.PATH, Type: "integer", Required: true, Example: 42},
+				{Name: "status", In: schema.QUERY, Type: "string", Enum: []string{"available", "sold"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"200": {Description: "The pet", Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+				"404": {Description: "Not found"},
+			},
+		},
+	}
+	swagger.Paths["/health"] = &schema.Path{
+		GET: &schema.Operation{
+			Security:  []map[string][]string{},
+			Responses: map[string]*schema.Responses{"200": {Description: "OK"}},
+		},
+	}
+
+	return swagger
+}
+
+func TestMarkdown(t *testing.T) {
+	b, err := Markdown(testSwagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := string(b)
+	for _, expected := range []string{
+		"# Pet Store (1.0)",
+		"Base URL: `https://api.example.com/v1`",
+		"| token | apiKey | header Authorization |  |",
+		"## pets\n\nEverything about pets",
+		"## default",
+		`<a id="operation-get-pets-id"></a>`,
+		"Security: token",
+		"| id | path | `integer` | yes |  |  |",
+		"| status | query | `string` |  |  | available, sold |",
+		"| 200 | The pet | [`Pet`](#definition-pet) |",
+		"curl -X GET 'https://api.example.com/v1/pets/42' -H 'Authorization: <token>'",
+		"curl -X GET 'https://api.example.com/v1/health'\n",
+		"| id | `integer(int64)` | yes |  |  |",
+		`| name | ` + "`string`" + ` | yes | Name \| nickname |  |`,
+	} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("missing %q in\n%s", expected, md)
+		}
+	}
+
+	if strings.Contains(md[strings.Index(md, "GET /health\n"):], "Security:") {
+		t.Error("operation without security lists the global requirement")
+	}
+}
+
+func TestHTML(t *testing.T) {
+	swagger := testSwagger()
+	swagger.Info.Description = "<script>alert(1)</script>"
+
+	b, err := HTML(swagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(b)
+	for _, expected := range []string{
+		`<h3 id="operation-get-pets-id"><span class="method GET">GET</span> /pets/{id}</h3>`,
+		`<a href="#definition-pet"><code>Pet</code></a>`,
+		`&lt;script&gt;`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("missing %q in\n%s", expected, html)
+		}
+	}
+}
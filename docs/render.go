@@ -0,0 +1,194 @@
+package docs
+
+import (
+	"bytes"
+	"github.com/peak6/arlong/schema"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// cell escapes a value for a Markdown table cell.
+func cell(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "|", `\|`, -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+func mdType(t typeRef) string {
+	if t.Anchor == "" {
+		return "`" + t.Text + "`"
+	}
+
+	return "[`" + t.Text + "`](#" + t.Anchor + ")"
+}
+
+func required(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return ""
+}
+
+var funcs = map[string]interface{}{
+	"cell":     cell,
+	"mdType":   mdType,
+	"required": required,
+}
+
+// Markdown renders swagger as a Markdown reference.
+func Markdown(swagger *schema.Swagger) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := markdownTpl.Execute(buf, build(swagger)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// HTML renders swagger as a single HTML page with inline styles.
+func HTML(swagger *schema.Swagger) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := htmlTpl.Execute(buf, build(swagger)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var markdownTpl = template.Must(template.New("markdown").Funcs(funcs).Parse(`# {{.Title}}{{if .Version}} ({{.Version}}){{end}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+Base URL: ` + "`{{.BaseURL}}`" + `
+
+## Contents
+{{range .Sections}}
+- {{.Name}}{{range .Operations}}
+  - [{{.Method}} {{.Path}}](#{{.Anchor}}){{end}}{{end}}
+- [Definitions](#definitions)
+{{if .Security}}
+## Security
+| Name | Type | Details | Scopes |
+| --- | --- | --- | --- |
+{{range .Security}}| {{cell .Name}} | {{.Type}} | {{cell .Details}} | {{range $i, $s := .Scopes}}{{if $i}}<br>{{end}}{{cell $s}}{{end}} |
+{{end}}{{end}}{{range .Sections}}
+## {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}{{range .Operations}}
+<a id="{{.Anchor}}"></a>
+### {{.Method}} {{.Path}}
+{{if .Deprecated}}
+**Deprecated**
+{{end}}{{if .Summary}}
+{{.Summary}}
+{{end}}{{if .Description}}
+{{.Description}}
+{{end}}{{if .OperationId}}
+Operation ID: ` + "`{{.OperationId}}`" + `
+{{end}}{{if .Security}}
+Security: {{range $i, $s := .Security}}{{if $i}} or {{end}}{{$s}}{{end}}
+{{end}}{{if .Params}}
+#### Parameters
+| Name | In | Type | Required | Description | Enum |
+| --- | --- | --- | --- | --- | --- |
+{{range .Params}}| {{cell .Name}} | {{.In}} | {{mdType .Type}} | {{required .Required}} | {{cell .Description}} | {{cell .Enum}} |
+{{end}}{{end}}{{if .Responses}}
+#### Responses
+| Code | Description | Type |
+| --- | --- | --- |
+{{range .Responses}}| {{.Code}} | {{cell .Description}} | {{if .Type.Text}}{{mdType .Type}}{{end}} |
+{{end}}{{end}}
+#### Example
+` + "```shell" + `
+{{.Curl}}
+` + "```" + `
+{{end}}{{end}}
+## Definitions
+{{range .Definitions}}
+<a id="{{.Anchor}}"></a>
+### {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}{{if .Properties}}
+| Name | Type | Required | Description | Enum |
+| --- | --- | --- | --- | --- |
+{{range .Properties}}| {{cell .Name}} | {{mdType .Type}} | {{required .Required}} | {{cell .Description}} | {{cell .Enum}} |
+{{end}}{{else}}
+Type: {{mdType .Type}}
+{{end}}{{end}}`))
+
+var htmlTpl = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+code, pre { background: #f5f5f5; border-radius: 3px; }
+pre { padding: 1em; overflow-x: auto; }
+.method { display: inline-block; min-width: 4em; padding: 2px 6px; border-radius: 3px; color: #fff; background: #555; font-size: 0.9em; }
+.GET { background: #2b7bb9; } .POST { background: #3a9d48; } .PUT { background: #c58a0a; } .DELETE { background: #c0392b; } .PATCH { background: #7d4cae; }
+.deprecated { color: #c0392b; font-weight: bold; }
+.description { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}{{if .Version}} <small>{{.Version}}</small>{{end}}</h1>
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}
+<p>Base URL: <code>{{.BaseURL}}</code></p>
+
+<h2>Contents</h2>
+<ul>
+{{range .Sections}}<li>{{.Name}}<ul>
+{{range .Operations}}<li><a href="#{{.Anchor}}">{{.Method}} {{.Path}}</a></li>
+{{end}}</ul></li>
+{{end}}<li><a href="#definitions">Definitions</a></li>
+</ul>
+{{if .Security}}
+<h2>Security</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Details</th><th>Scopes</th></tr>
+{{range .Security}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Details}}</td><td>{{range $i, $s := .Scopes}}{{if $i}}<br>{{end}}{{$s}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Sections}}
+<h2>{{.Name}}</h2>
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}
+{{range .Operations}}
+<h3 id="{{.Anchor}}"><span class="method {{.Method}}">{{.Method}}</span> {{.Path}}</h3>
+{{if .Deprecated}}<p class="deprecated">Deprecated</p>{{end}}
+{{if .Summary}}<p>{{.Summary}}</p>{{end}}
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}
+{{if .OperationId}}<p>Operation ID: <code>{{.OperationId}}</code></p>{{end}}
+{{if .Security}}<p>Security: {{range $i, $s := .Security}}{{if $i}} or {{end}}{{$s}}{{end}}</p>{{end}}
+{{if .Params}}<h4>Parameters</h4>
+<table>
+<tr><th>Name</th><th>In</th><th>Type</th><th>Required</th><th>Description</th><th>Enum</th></tr>
+{{range .Params}}<tr><td>{{.Name}}</td><td>{{.In}}</td><td>{{template "type" .Type}}</td><td>{{required .Required}}</td><td>{{.Description}}</td><td>{{.Enum}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Responses}}<h4>Responses</h4>
+<table>
+<tr><th>Code</th><th>Description</th><th>Type</th></tr>
+{{range .Responses}}<tr><td>{{.Code}}</td><td>{{.Description}}</td><td>{{if .Type.Text}}{{template "type" .Type}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}<h4>Example</h4>
+<pre><code>{{.Curl}}</code></pre>
+{{end}}{{end}}
+<h2 id="definitions">Definitions</h2>
+{{range .Definitions}}
+<h3 id="{{.Anchor}}">{{.Name}}</h3>
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}
+{{if .Properties}}<table>
+<tr><th>Name</th><th>Type</th><th>Required</th><th>Description</th><th>Enum</th></tr>
+{{range .Properties}}<tr><td>{{.Name}}</td><td>{{template "type" .Type}}</td><td>{{required .Required}}</td><td>{{.Description}}</td><td>{{.Enum}}</td></tr>
+{{end}}</table>
+{{else}}<p>Type: {{template "type" .Type}}</p>
+{{end}}{{end}}
+</body>
+</html>
+{{define "type"}}{{if .Anchor}}<a href="#{{.Anchor}}"><code>{{.Text}}</code></a>{{else}}<code>{{.Text}}</code>{{end}}{{end}}
+`))
@@ -0,0 +1,106 @@
+// Package goname turns names from a swagger document into Go identifiers.
+// It is shared by the generators that write Go source, so a definition or
+// property gets the same identifier everywhere.
+package goname
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Identifier turns a name into an exported Go identifier: user_id and
+// user-id become UserId, a name starting with a digit is prefixed with N.
+func Identifier(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	name := ""
+	for _, part := range parts {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		name += string(runes)
+	}
+
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		name = "N" + name
+	}
+
+	return name
+}
+
+// Scope hands out identifiers that are unique within it, e.g. the fields of
+// a struct or the declarations of a file.
+type Scope struct {
+	taken map[string]bool
+}
+
+// NewScope returns a scope in which reserved are already taken.
+func NewScope(reserved ...string) *Scope {
+	s := &Scope{taken: make(map[string]bool)}
+	for _, name := range reserved {
+		s.taken[name] = true
+	}
+
+	return s
+}
+
+// Taken reports whether any of names is taken.
+func (s *Scope) Taken(names ...string) bool {
+	for _, name := range names {
+		if s.taken[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Take marks names as taken.
+func (s *Scope) Take(names ...string) {
+	for _, name := range names {
+		s.taken[name] = true
+	}
+}
+
+// Unique returns the identifier of name, followed by the smallest number
+// from 2 on when it is already taken, and takes it. user_id and userId are
+// given UserId and UserId2 in the order they are asked for.
+func (s *Scope) Unique(name string) string {
+	base := Identifier(name)
+	ident := base
+	for i := 2; s.taken[ident]; i++ {
+		ident = base + strconv.Itoa(i)
+	}
+	s.taken[ident] = true
+
+	return ident
+}
+
+// Definitions names the definitions keys within scope: by the last segment
+// of the key, or by the whole key when several keys share that segment.
+func Definitions(keys []string, scope *Scope) map[string]string {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+
+	short := func(key string) string {
+		return Identifier(key[strings.LastIndex(key, ".")+1:])
+	}
+	count := make(map[string]int)
+	for _, key := range sorted {
+		count[short(key)]++
+	}
+
+	names := make(map[string]string, len(sorted))
+	for _, key := range sorted {
+		if count[short(key)] == 1 {
+			names[key] = scope.Unique(short(key))
+		} else {
+			names[key] = scope.Unique(key)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,51 @@
+package goname
+
+import "testing"
+
+func TestIdentifier(t *testing.T) {
+	for name, want := range map[string]string{
+		"user_id":    "UserId",
+		"user-id":    "UserId",
+		"userId":     "UserId",
+		"get /pets":  "GetPets",
+		"2fa":        "N2fa",
+		"":           "N",
+		"_":          "N",
+		"naïve name": "NaïveName",
+	} {
+		if got := Identifier(name); got != want {
+			t.Errorf("%q: expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestScope(t *testing.T) {
+	scope := NewScope("Handler")
+	for _, want := range [][2]string{
+		{"user_id", "UserId"},
+		{"userId", "UserId2"},
+		{"user-id", "UserId3"},
+		{"handler", "Handler2"},
+	} {
+		if got := scope.Unique(want[0]); got != want[1] {
+			t.Errorf("%q: expected %s, got %s", want[0], want[1], got)
+		}
+	}
+	if !scope.Taken("Nope", "UserId2") || scope.Taken("Nope") {
+		t.Error("unexpected taken names")
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	names := Definitions([]string{"models.User", "other.User", "models.Pet", "Pet_"}, NewScope("Pet"))
+	for key, want := range map[string]string{
+		"models.User": "ModelsUser",
+		"other.User":  "OtherUser",
+		"models.Pet":  "ModelsPet",
+		"Pet_":        "Pet2",
+	} {
+		if names[key] != want {
+			t.Errorf("%s: expected %s, got %s", key, want, names[key])
+		}
+	}
+}
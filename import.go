@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/schema"
+	"github.com/peak6/arlong/spec"
+	"io/ioutil"
+	"os"
+)
+
+var importCommand = cli.Command{
+	Name:  "import",
+	Usage: "Write annotations and struct skeletons for an existing spec: arlong import spec.json",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "out, o",
+			Value: "annotations.go",
+			Usage: "Output file",
+		},
+
+		cli.StringFlag{
+			Name:  "package",
+			Value: "api",
+			Usage: "Package name of the generated file",
+		},
+	},
+	Action: func(c *cli.Context) {
+		if err := importSpec(c.Args().First(), c.String("package"), c.String("out")); err != nil {
+			os.Stderr.WriteString(err.Error())
+		}
+	},
+}
+
+func importSpec(file, pkg, out string) error {
+	if file == "" {
+		return errors.New("Missing spec file, usage: arlong import spec.json")
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	swagger := schema.New()
+	if err := json.Unmarshal(b, swagger); err != nil {
+		return err
+	}
+
+	src, err := spec.Annotate(swagger, pkg)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -1,25 +1,64 @@
 package main
 
 import (
+	"encoding/json"
 	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/schema"
 	"github.com/peak6/arlong/spec"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 )
 
+var parserFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "path, p",
+		Value: ".",
+		Usage: "Package path to generate",
+	},
+
+	cli.StringFlag{
+		Name:  "naming, n",
+		Value: "full",
+		Usage: "Definition naming strategy (full, package, short)",
+	},
+
+	cli.StringFlag{
+		Name:  "roots",
+		Value: "",
+		Usage: "Comma separated definitions to keep even when no operation uses them",
+	},
+
+	cli.StringFlag{
+		Name:  "typemap",
+		Value: "",
+		Usage: "JSON file mapping Go types to schemas",
+	},
+
+	cli.BoolFlag{
+		Name:  "infer-required",
+		Usage: "Mark struct fields required unless they are pointers or omitempty",
+	},
+
+	cli.BoolFlag{
+		Name:  "hoist-inline",
+		Usage: "Move inline struct types into definitions named after their parent and field",
+	},
+}
+
+var specFlag = cli.StringFlag{
+	Name:  "spec, s",
+	Value: "",
+	Usage: "Swagger JSON file to use instead of parsing --path",
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Version = "1.0.1"
 	app.Name = "arlong"
 	app.Usage = "Genrate Swagger 2.0"
-	app.Flags = []cli.Flag{
-		cli.StringFlag{
-			Name:  "path, p",
-			Value: ".",
-			Usage: "Package path to generate",
-		},
-
+	app.Flags = append([]cli.Flag{
 		cli.StringFlag{
 			Name:  "out, o",
 			Value: ".",
@@ -31,10 +70,35 @@ func main() {
 			Value: "swagger.json",
 			Usage: "Output file name",
 		},
+
+		cli.BoolFlag{
+			Name:  "prune",
+			Usage: "Remove components that no operation uses",
+		},
+
+		cli.BoolFlag{
+			Name:  "examples",
+			Usage: "Generate an example for every definition and response",
+		},
+	}, parserFlags...)
+	app.Commands = []cli.Command{
+		unusedCommand,
+		mockCommand,
+		coverageCommand,
+		serverCommand,
+		docsCommand,
+		postmanCommand,
+		importCommand,
 	}
 	app.Action = func(c *cli.Context) {
-		p := c.String("path")
-		parser := spec.NewParser(p)
+		parser, err := newParser(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+		parser.Prune = c.Bool("prune")
+		parser.Examples = c.Bool("examples")
+
 		b, err := parser.JSON()
 		if err != nil {
 			os.Stderr.WriteString(err.Error())
@@ -49,3 +113,49 @@ func main() {
 
 	app.Run(os.Args)
 }
+
+func newParser(c *cli.Context) (*spec.Parser, error) {
+	parser := spec.NewParser(c.String("path"))
+	naming, err := spec.ParseNamingStrategy(c.String("naming"))
+	if err != nil {
+		return nil, err
+	}
+	parser.Naming = naming
+	parser.InferRequired = c.Bool("infer-required")
+	parser.HoistInline = c.Bool("hoist-inline")
+
+	if roots := c.String("roots"); roots != "" {
+		parser.Roots = strings.Split(roots, ",")
+	}
+
+	if file := c.String("typemap"); file != "" {
+		if parser.TypeMap, err = spec.LoadTypeMap(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return parser, nil
+}
+
+// loadSwagger reads the document given with --spec, or parses --path.
+func loadSwagger(c *cli.Context) (*schema.Swagger, error) {
+	if file := c.String("spec"); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		swagger := schema.New()
+		if err := json.Unmarshal(b, swagger); err != nil {
+			return nil, err
+		}
+		return swagger, nil
+	}
+
+	parser, err := newParser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.Swagger()
+}
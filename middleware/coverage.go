@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/peak6/arlong/schema"
+	"sort"
+	"strconv"
+)
+
+// Coverage compares recorded hits with the operations of a document.
+// Operations are named by operationId, or "METHOD /template" when they
+// have none.
+type Coverage struct {
+	Operations int
+	Exercised  int
+	Responses  int
+	Seen       int
+
+	// Undocumented lists requests no operation matched, as "METHOD /path".
+	Undocumented []string
+	// Unexercised lists operations no request hit.
+	Unexercised []string
+	// Unseen lists declared responses no request got, as "operation code".
+	Unseen []string
+}
+
+func operationName(rt *Route) string {
+	if rt.Operation.OperationId != "" {
+		return rt.Operation.OperationId
+	}
+
+	return rt.Method + " " + rt.Template
+}
+
+// NewCoverage reports how much of swagger the hits exercised. A "default"
+// response counts as seen when a request got a code the operation does
+// not declare.
+func NewCoverage(swagger *schema.Swagger, hits []*Hit) *Coverage {
+	c := &Coverage{}
+	seen := make(map[string]map[string]bool)
+	undocumented := make(map[string]bool)
+	for _, hit := range hits {
+		if !hit.Documented {
+			undocumented[hit.Method+" "+hit.Path] = true
+			continue
+		}
+		key := hit.Method + " " + hit.Path
+		if seen[key] == nil {
+			seen[key] = make(map[string]bool)
+		}
+		seen[key][strconv.Itoa(hit.Code)] = true
+	}
+
+	for _, rt := range NewRouter(swagger).Routes() {
+		name := operationName(rt)
+		codes := seen[rt.Method+" "+rt.Template]
+
+		c.Operations++
+		if len(codes) > 0 {
+			c.Exercised++
+		} else {
+			c.Unexercised = append(c.Unexercised, name)
+		}
+
+		undeclared := false
+		for code := range codes {
+			if _, ok := rt.Operation.Responses[code]; !ok {
+				undeclared = true
+			}
+		}
+
+		for code := range rt.Operation.Responses {
+			c.Responses++
+			if codes[code] || (code == "default" && undeclared) {
+				c.Seen++
+			} else {
+				c.Unseen = append(c.Unseen, name+" "+code)
+			}
+		}
+	}
+
+	for name := range undocumented {
+		c.Undocumented = append(c.Undocumented, name)
+	}
+
+	sort.Strings(c.Undocumented)
+	sort.Strings(c.Unexercised)
+	sort.Strings(c.Unseen)
+
+	return c
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return float64(n) * 100 / float64(total)
+}
+
+// OperationPercent is the share of operations that were exercised.
+func (c *Coverage) OperationPercent() float64 {
+	return percent(c.Exercised, c.Operations)
+}
+
+// ResponsePercent is the share of declared responses that were seen.
+func (c *Coverage) ResponsePercent() float64 {
+	return percent(c.Seen, c.Responses)
+}
+
+func (c *Coverage) String() string {
+	return fmt.Sprintf("operations: %d/%d (%.1f%%), responses: %d/%d (%.1f%%)",
+		c.Exercised, c.Operations, c.OperationPercent(), c.Seen, c.Responses, c.ResponsePercent())
+}
@@ -0,0 +1,162 @@
+// Package middleware enforces a Swagger document at runtime. Requests that
+// do not match the parameters of their operation are rejected with a 400,
+// and responses can be checked against the declared schemas.
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Error is one way a request or response does not match the document. For
+// bodies Name is the JSON pointer of the offending value.
+type Error struct {
+	In      string `json:"in"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: %s", e.In, e.Message)
+	}
+
+	return fmt.Sprintf("%s %s: %s", e.In, e.Name, e.Message)
+}
+
+// ErrorBody is the JSON body of the responses written by WriteError.
+type ErrorBody struct {
+	Message string   `json:"message"`
+	Errors  []*Error `json:"errors,omitempty"`
+}
+
+// WriteError writes a JSON error response.
+func WriteError(w http.ResponseWriter, status int, message string, errs ...*Error) {
+	w.Header().Set("Content-Type", schema.MIME_JSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&ErrorBody{Message: message, Errors: errs})
+}
+
+// Validator checks requests and responses against a document.
+type Validator struct {
+	// CheckResponses makes Handler check every response against the schema
+	// declared for its status code.
+	CheckResponses bool
+
+	// ResponseError is called with the problems of a response when
+	// CheckResponses is set. They are logged when it is nil. Tests can use
+	// it to fail on responses that drift from the document.
+	ResponseError func(r *http.Request, errs []*Error)
+
+	swagger *schema.Swagger
+	router  *Router
+}
+
+func New(swagger *schema.Swagger) *Validator {
+	return &Validator{
+		swagger: swagger,
+		router:  NewRouter(swagger),
+	}
+}
+
+// Swagger returns the document the validator enforces.
+func (v *Validator) Swagger() *schema.Swagger {
+	return v.swagger
+}
+
+// Router returns the router matching requests to operations.
+func (v *Validator) Router() *Router {
+	return v.router
+}
+
+// Handler wraps next. Requests for operations of the document are checked
+// first and rejected with a 400 listing the errors. Requests the document
+// does not describe are passed through unchecked.
+func (v *Validator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, vars, _ := v.router.Match(r.Method, r.URL.Path)
+		if rt == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if errs := v.CheckRequest(rt, r, vars); len(errs) > 0 {
+			WriteError(w, http.StatusBadRequest, "Invalid request", errs...)
+			return
+		}
+
+		if !v.CheckResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if errs := v.CheckResponse(rt, rec.status, rec.Header(), rec.body.Bytes()); len(errs) > 0 {
+			if v.ResponseError != nil {
+				v.ResponseError(r, errs)
+				return
+			}
+			for _, err := range errs {
+				logrus.Errorf("%s %s: %s", r.Method, r.URL.Path, err)
+			}
+		}
+	})
+}
+
+// Response returns the response an operation declares for a status code,
+// falling back to "default", with $refs resolved.
+func (v *Validator) Response(op *schema.Operation, status int) *schema.Responses {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp = op.Responses["default"]
+	}
+	if resp != nil && resp.Ref != "" {
+		resp = v.swagger.Responses[strings.TrimPrefix(resp.Ref, "#/responses/")]
+	}
+
+	return resp
+}
+
+// CheckResponse validates a response of an operation: its status code
+// must be declared, and a JSON body must match the declared schema.
+func (v *Validator) CheckResponse(rt *Route, status int, header http.Header, body []byte) []*Error {
+	resp := v.Response(rt.Operation, status)
+	if resp == nil {
+		return []*Error{{In: "response", Message: fmt.Sprintf("status %d is not declared", status)}}
+	}
+
+	if mime := header.Get("Content-Type"); resp.Schema == nil || (mime != "" && !strings.Contains(mime, "json")) {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []*Error{{In: "response", Message: "missing body"}}
+	}
+
+	return v.checkJSON("response", resp.Schema, body)
+}
+
+// recorder keeps a copy of a response while it is written.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
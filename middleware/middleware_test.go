@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/peak6/arlong/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSwagger() *schema.Swagger {
+	swagger := schema.New()
+	swagger.Definitions["Pet"] = &schema.Schema{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]*schema.Schema{
+			"name": {Type: "string"},
+			"age":  {Type: "integer"},
+		},
+	}
+	swagger.Parameters["limit"] = &schema.Parameter{Name: "limit", In: schema.QUERY, Type: "integer", Maximum: 100}
+	swagger.Paths["/pets"] = &schema.Path{
+		GET: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Ref: "#/parameters/limit"},
+				{Name: "X-Tenant", In: schema.HEADER, Type: "string", Required: true},
+			},
+			Responses: map[string]*schema.Responses{
+				"200": {Schema: &schema.Schema{Type: "array", Items: &schema.Schema{Ref: "#/definitions/Pet"}}},
+			},
+		},
+		POST: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "body", In: "body", Required: true, Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"201": {Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+		},
+	}
+
+	return swagger
+}
+
+func TestRequestValidation(t *testing.T) {
+	var received string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := New(testSwagger()).Handler(next)
+
+	req := httptest.NewRequest("GET", "/pets?limit=500", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+
+	body := &ErrorBody{}
+	json.Unmarshal(w.Body.Bytes(), body)
+	if len(body.Errors) != 2 || body.Errors[0].Name != "limit" || body.Errors[1].Name != "X-Tenant" {
+		t.Fatalf("Unexpected errors %+v", body.Errors)
+	}
+
+	req = httptest.NewRequest("POST", "/pets", strings.NewReader(`{"age": "old"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	body = &ErrorBody{}
+	json.Unmarshal(w.Body.Bytes(), body)
+	if w.Code != http.StatusBadRequest || len(body.Errors) != 2 || body.Errors[0].In != "body" {
+		t.Fatalf("Expected 2 body errors, got %d %+v", w.Code, body.Errors)
+	}
+
+	req = httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name": "rex"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || received != `{"name": "rex"}` {
+		t.Fatalf("Expected the body to reach the handler, got %d %q", w.Code, received)
+	}
+
+	req = httptest.NewRequest("GET", "/unknown", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected undocumented routes to pass through, got %d", w.Code)
+	}
+}
+
+func TestParameterFormats(t *testing.T) {
+	swagger := schema.New()
+	swagger.Paths["/search"] = &schema.Path{
+		GET: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "tags", In: schema.QUERY, Type: "array", CollectionFormat: "pipes", MaxItems: 2, Items: &schema.Items{Type: "string", Enum: []string{"a", "b"}}},
+				{Name: "ids", In: schema.QUERY, Type: "array", CollectionFormat: "multi", Items: &schema.Items{Type: "integer"}},
+				{Name: "city", In: schema.QUERY, Type: "string", MaxLength: 4},
+			},
+		},
+		POST: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "note", In: schema.FORMDATA, Type: "string"},
+			},
+		},
+	}
+	handler := New(swagger).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for url, code := range map[string]int{
+		"/search?tags=a|b&ids=1&ids=2&city=Köln": http.StatusOK,
+		"/search?tags=a,b":                       http.StatusBadRequest,
+		"/search?tags=a|b|a":                     http.StatusBadRequest,
+		"/search?ids=1,2":                        http.StatusBadRequest,
+		"/search?city=Paris":                     http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d %s", url, code, w.Code, w.Body)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/search", strings.NewReader("--x\r\nbroken"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "cannot parse the form") {
+		t.Errorf("expected a broken form to be rejected, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestResponseValidation(t *testing.T) {
+	validator := New(testSwagger())
+	validator.CheckResponses = true
+
+	var errs []*Error
+	validator.ResponseError = func(r *http.Request, e []*Error) {
+		errs = e
+	}
+
+	handler := validator.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", schema.MIME_JSON)
+		w.Write([]byte(`[{"name": "rex"}, {"age": 2}]`))
+	}))
+
+	req := httptest.NewRequest("GET", "/pets", nil)
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if len(errs) != 1 || errs[0].Name != "/1" {
+		t.Fatalf("Expected a missing name at /1, got %v", errs)
+	}
+
+	errs = nil
+	req = httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name": "rex"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "200 is not declared") {
+		t.Fatalf("Expected an undeclared status, got %v", errs)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	swagger := testSwagger()
+	swagger.Paths["/pets"].GET.OperationId = "listPets"
+	swagger.Paths["/pets"].GET.Responses["default"] = &schema.Responses{}
+
+	file := filepath.Join(t.TempDir(), "arlong.record")
+	handler := NewRecording(swagger, file).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pets" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	for _, url := range []string{"/pets", "/pets", "/health"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+
+	hits, err := ReadHits(file)
+	if err != nil || len(hits) != 3 {
+		t.Fatalf("Expected 3 hits, got %v %v", hits, err)
+	}
+	if hits[0].OperationId != "listPets" || hits[0].Code != http.StatusServiceUnavailable || hits[2].Documented {
+		t.Errorf("Unexpected hits %+v %+v", hits[0], hits[2])
+	}
+
+	coverage := NewCoverage(swagger, hits)
+	if coverage.Exercised != 1 || coverage.Operations != 2 || coverage.Seen != 1 || coverage.Responses != 3 {
+		t.Errorf("Unexpected coverage %s", coverage)
+	}
+	if !reflect.DeepEqual(coverage.Undocumented, []string{"GET /health"}) ||
+		!reflect.DeepEqual(coverage.Unexercised, []string{"POST /pets"}) ||
+		!reflect.DeepEqual(coverage.Unseen, []string{"POST /pets 201", "listPets 200"}) {
+		t.Errorf("Unexpected coverage %+v", coverage)
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/schema"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parameters returns the parameters of a route with $refs resolved. Those
+// declared on the operation override the ones declared on the path.
+func (v *Validator) Parameters(rt *Route) []*schema.Parameter {
+	params := []*schema.Parameter{}
+	index := make(map[string]int)
+	add := func(param *schema.Parameter) {
+		if param.Ref != "" {
+			param = v.swagger.Parameters[strings.TrimPrefix(param.Ref, "#/parameters/")]
+			if param == nil {
+				return
+			}
+		}
+
+		key := param.In + " " + param.Name
+		if i, ok := index[key]; ok {
+			params[i] = param
+			return
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+
+	for i := range rt.Path.Parameters {
+		add(&rt.Path.Parameters[i])
+	}
+	for _, param := range rt.Operation.Parameters {
+		add(param)
+	}
+
+	return params
+}
+
+// CheckRequest validates a request against the parameters of its route and
+// returns what is wrong with it. vars are the path parameter values found
+// by Match. The body is read and put back for the next handler.
+func (v *Validator) CheckRequest(rt *Route, r *http.Request, vars map[string]string) []*Error {
+	errs := []*Error{}
+	params := v.Parameters(rt)
+	for _, param := range params {
+		if param.In == schema.FORMDATA {
+			err := r.ParseMultipartForm(32 << 20)
+			if err != nil && err != http.ErrNotMultipart {
+				return []*Error{{In: schema.FORMDATA, Message: fmt.Sprintf("cannot parse the form: %s", err)}}
+			}
+			break
+		}
+	}
+
+	for _, param := range params {
+		if param.In == "body" {
+			errs = append(errs, v.checkBody(param, r)...)
+			continue
+		}
+
+		var values []string
+		switch param.In {
+		case schema.PATH:
+			if raw, ok := vars[param.Name]; ok {
+				values = []string{raw}
+			}
+		case schema.QUERY:
+			values = r.URL.Query()[param.Name]
+		case schema.HEADER:
+			if raw := r.Header.Get(param.Name); raw != "" {
+				values = []string{raw}
+			}
+		case schema.FORMDATA:
+			values = r.Form[param.Name]
+		}
+
+		if len(values) == 0 || (values[0] == "" && !param.AllowEmptyValue) {
+			if param.Required {
+				errs = append(errs, &Error{In: param.In, Name: param.Name, Message: "required"})
+			}
+			continue
+		}
+
+		for _, problem := range checkParam(param, values) {
+			errs = append(errs, &Error{In: param.In, Name: param.Name, Message: problem})
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) checkBody(param *schema.Parameter, r *http.Request) []*Error {
+	if r.Body == nil {
+		r.Body = ioutil.NopCloser(&bytes.Buffer{})
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return []*Error{{In: "body", Message: err.Error()}}
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		if param.Required {
+			return []*Error{{In: "body", Message: "required"}}
+		}
+		return nil
+	}
+
+	return v.checkJSON("body", param.Schema, b)
+}
+
+// checkJSON checks a JSON document against s. Errors are named after the
+// JSON pointer of the value they are about.
+func (v *Validator) checkJSON(in string, s *schema.Schema, b []byte) []*Error {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return []*Error{{In: in, Message: fmt.Sprintf("invalid JSON: %s", err)}}
+	}
+
+	errs := []*Error{}
+	for _, violation := range s.Validate(value, v.swagger) {
+		errs = append(errs, &Error{In: in, Name: violation.Path, Message: violation.Message})
+	}
+
+	return errs
+}
+
+// checkParam checks the raw values of a path, query, header or form
+// parameter. Arrays are split by their collectionFormat.
+func checkParam(param *schema.Parameter, raw []string) []string {
+	if param.Type != "array" {
+		return checkValue(param.Type, raw[0], param.Enum, param.Minimum, param.Maximum, param.MinLength, param.MaxLength)
+	}
+
+	values := raw
+	if sep := param.Separator(); sep != "" {
+		values = []string{}
+		for _, val := range raw {
+			values = append(values, strings.Split(val, sep)...)
+		}
+	}
+
+	problems := []string{}
+	if param.MinItems > 0 && len(values) < param.MinItems {
+		problems = append(problems, fmt.Sprintf("expected at least %d items", param.MinItems))
+	}
+	if param.MaxItems > 0 && len(values) > param.MaxItems {
+		problems = append(problems, fmt.Sprintf("expected at most %d items", param.MaxItems))
+	}
+
+	if item := param.Items; item != nil {
+		for _, value := range values {
+			problems = append(problems, checkValue(item.Type, value, item.Enum, item.Minimum, item.Maximum, item.MinLength, item.MaxLength)...)
+		}
+	}
+
+	return problems
+}
+
+func checkValue(typ, raw string, enum []string, minimum, maximum, minLength, maxLength int) []string {
+	problems := []string{}
+	switch typ {
+	case "integer":
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("expected an integer, got %q", raw)}
+		}
+		problems = append(problems, checkRange(float64(i), minimum, maximum)...)
+	case "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("expected a number, got %q", raw)}
+		}
+		problems = append(problems, checkRange(f, minimum, maximum)...)
+	case "boolean":
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return []string{fmt.Sprintf("expected a boolean, got %q", raw)}
+		}
+	default:
+		length := utf8.RuneCountInString(raw)
+		if minLength > 0 && length < minLength {
+			problems = append(problems, fmt.Sprintf("expected at least %d characters", minLength))
+		}
+		if maxLength > 0 && length > maxLength {
+			problems = append(problems, fmt.Sprintf("expected at most %d characters", maxLength))
+		}
+	}
+
+	if len(enum) > 0 {
+		found := false
+		for _, val := range enum {
+			if val == raw {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%q is not one of %v", raw, enum))
+		}
+	}
+
+	return problems
+}
+
+func checkRange(val float64, minimum, maximum int) []string {
+	if minimum != 0 && val < float64(minimum) {
+		return []string{fmt.Sprintf("%v is less than %d", val, minimum)}
+	}
+	if maximum != 0 && val > float64(maximum) {
+		return []string{fmt.Sprintf("%v is greater than %d", val, maximum)}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/Sirupsen/logrus"
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Hit is one request seen by a Recording.
+type Hit struct {
+	Method string `json:"method"`
+	// Path is the path template of the operation, or the request path
+	// when no operation matched.
+	Path        string `json:"path"`
+	OperationId string `json:"operationId,omitempty"`
+	Code        int    `json:"code"`
+	Documented  bool   `json:"documented"`
+}
+
+// Recording appends every request it sees to a file, one JSON Hit per
+// line, so test runs of several packages can share it.
+type Recording struct {
+	router *Router
+	file   string
+	mu     sync.Mutex
+}
+
+func NewRecording(swagger *schema.Swagger, file string) *Recording {
+	return &Recording{
+		router: NewRouter(swagger),
+		file:   file,
+	}
+}
+
+// Handler wraps next and records the operation and status code of every
+// request.
+func (rec *Recording) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(resp, r)
+
+		hit := &Hit{Method: r.Method, Path: r.URL.Path, Code: resp.status}
+		if rt, _, _ := rec.router.Match(r.Method, r.URL.Path); rt != nil {
+			hit.Path, hit.OperationId, hit.Documented = rt.Template, rt.Operation.OperationId, true
+		}
+
+		if err := rec.record(hit); err != nil {
+			logrus.Errorf("Recording %s %s: %s", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+func (rec *Recording) record(hit *Hit) error {
+	b, err := json.Marshal(hit)
+	if err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	f, err := os.OpenFile(rec.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// ReadHits reads a file written by a Recording.
+func ReadHits(file string) ([]*Hit, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hits := []*Hit{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		hit := &Hit{}
+		if err := json.Unmarshal(scanner.Bytes(), hit); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, scanner.Err()
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Route is one operation of a document.
+type Route struct {
+	Method    string
+	Template  string
+	Path      *schema.Path
+	Operation *schema.Operation
+	segments  []string
+}
+
+// Router matches requests to the operations of a document.
+type Router struct {
+	routes []*Route
+}
+
+// Operations returns the operations of a path by HTTP method.
+func Operations(path *schema.Path) map[string]*schema.Operation {
+	return map[string]*schema.Operation{
+		http.MethodGet:     path.GET,
+		http.MethodPut:     path.PUT,
+		http.MethodPost:    path.POST,
+		http.MethodDelete:  path.DELETE,
+		http.MethodOptions: path.OPTIONS,
+		http.MethodHead:    path.HEAD,
+		http.MethodPatch:   path.PATCH,
+	}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func literals(segments []string) int {
+	count := 0
+	for _, segment := range segments {
+		if !isTemplate(segment) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// NewRouter collects the operations of swagger. Templates with more literal
+// segments are tried first, so /users/me wins over /users/{id}.
+func NewRouter(swagger *schema.Swagger) *Router {
+	r := &Router{}
+	for template, path := range swagger.Paths {
+		for method, op := range Operations(path) {
+			if op == nil {
+				continue
+			}
+			r.routes = append(r.routes, &Route{
+				Method:    method,
+				Template:  template,
+				Path:      path,
+				Operation: op,
+				segments:  splitPath(swagger.BasePath + template),
+			})
+		}
+	}
+
+	sort.Slice(r.routes, func(i, j int) bool {
+		a, b := r.routes[i], r.routes[j]
+		if la, lb := literals(a.segments), literals(b.segments); la != lb {
+			return la > lb
+		}
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		return a.Method < b.Method
+	})
+
+	return r
+}
+
+// Routes returns every operation in matching order.
+func (r *Router) Routes() []*Route {
+	return r.routes
+}
+
+func (rt *Route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+
+	vars := make(map[string]string)
+	for i, segment := range rt.segments {
+		if isTemplate(segment) {
+			vars[strings.Trim(segment, "{}")] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return vars, true
+}
+
+// Match finds the operation for a request and the values of its path
+// parameters. found is true when the path matched but the method did not.
+func (r *Router) Match(method, urlPath string) (rt *Route, vars map[string]string, found bool) {
+	segments := splitPath(urlPath)
+	for _, candidate := range r.routes {
+		v, ok := candidate.match(segments)
+		if !ok {
+			continue
+		}
+		found = true
+		if candidate.Method == method {
+			return candidate, v, true
+		}
+	}
+
+	return nil, nil, found
+}
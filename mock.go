@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/mock"
+	"log"
+	"net/http"
+	"os"
+)
+
+var mockCommand = cli.Command{
+	Name:  "mock",
+	Usage: "Serve every operation with its examples or synthesized payloads",
+	Flags: append([]cli.Flag{
+		specFlag,
+
+		cli.StringFlag{
+			Name:  "addr, a",
+			Value: ":8080",
+			Usage: "Address to listen on",
+		},
+	}, parserFlags...),
+	Action: func(c *cli.Context) {
+		swagger, err := loadSwagger(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		log.Printf("Serving mock API on %s", c.String("addr"))
+		if err := http.ListenAndServe(c.String("addr"), mock.New(swagger)); err != nil {
+			os.Stderr.WriteString(err.Error())
+		}
+	},
+}
@@ -0,0 +1,172 @@
+// Package mock serves the operations of a Swagger document with their
+// examples, so clients can be built before the real server exists.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Server is an http.Handler answering every operation of a document.
+// Requests are checked by the validation middleware and answered
+// with the declared example of the selected response, or a payload
+// synthesized from its schema.
+//
+// The response defaults to the lowest 2xx code. A "Prefer: code=404"
+// header selects another one.
+type Server struct {
+	swagger   *schema.Swagger
+	validator *middleware.Validator
+}
+
+func New(swagger *schema.Swagger) *Server {
+	return &Server{
+		swagger:   swagger,
+		validator: middleware.New(swagger),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, vars, found := s.validator.Router().Match(r.Method, r.URL.Path)
+	if rt == nil {
+		if found {
+			middleware.WriteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path))
+			return
+		}
+		middleware.WriteError(w, http.StatusNotFound, fmt.Sprintf("No operation matches %s", r.URL.Path))
+		return
+	}
+
+	if errs := s.validator.CheckRequest(rt, r, vars); len(errs) > 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request", errs...)
+		return
+	}
+
+	code, resp := s.selectResponse(rt.Operation, preferredCode(r))
+	if resp == nil {
+		middleware.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s %s declares no response %s", rt.Method, rt.Template, code))
+		return
+	}
+
+	s.writeResponse(w, r, rt.Operation, code, resp)
+}
+
+// preferredCode reads the code preference of a "Prefer: code=404" header.
+func preferredCode(r *http.Request) string {
+	for _, header := range r.Header["Prefer"] {
+		for _, pref := range strings.FieldsFunc(header, func(c rune) bool { return c == ',' || c == ';' }) {
+			kv := strings.SplitN(strings.TrimSpace(pref), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "code" {
+				return strings.Trim(strings.TrimSpace(kv[1]), `"`)
+			}
+		}
+	}
+
+	return ""
+}
+
+// selectResponse picks the response to send. Without a preference it is
+// the lowest 2xx code, then "default", then the lowest declared code. A
+// preferred code that is not declared falls back to "default".
+func (s *Server) selectResponse(op *schema.Operation, preferred string) (string, *schema.Responses) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	code := preferred
+	if code == "" {
+		for _, c := range codes {
+			if strings.HasPrefix(c, "2") {
+				code = c
+				break
+			}
+		}
+	}
+	if code == "" && len(codes) > 0 {
+		code = codes[0]
+		if _, ok := op.Responses["default"]; ok {
+			code = "default"
+		}
+	}
+
+	resp, ok := op.Responses[code]
+	if !ok && preferred != "" {
+		resp = op.Responses["default"]
+	}
+	if resp != nil && resp.Ref != "" {
+		resp = s.swagger.Responses[strings.TrimPrefix(resp.Ref, "#/responses/")]
+	}
+
+	return code, resp
+}
+
+func statusCode(code string) int {
+	if status, err := strconv.Atoi(code); err == nil {
+		return status
+	}
+
+	return http.StatusOK
+}
+
+// responseMime picks the content type to answer with, preferring one the
+// request accepts.
+func (s *Server) responseMime(r *http.Request, op *schema.Operation, resp *schema.Responses) string {
+	produces := op.Produces
+	if len(produces) == 0 {
+		produces = s.swagger.Produces
+	}
+
+	accept := r.Header.Get("Accept")
+	for _, mime := range produces {
+		if strings.Contains(accept, mime) {
+			return mime
+		}
+	}
+	for mime := range resp.Examples {
+		if strings.Contains(accept, mime) {
+			return mime
+		}
+	}
+	for _, mime := range produces {
+		if strings.Contains(mime, "json") {
+			return mime
+		}
+	}
+
+	return schema.MIME_JSON
+}
+
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, op *schema.Operation, code string, resp *schema.Responses) {
+	for name, header := range resp.Headers {
+		if header.Default != "" {
+			w.Header().Set(name, header.Default)
+		}
+	}
+
+	mime := s.responseMime(r, op, resp)
+	body, ok := resp.Examples[mime]
+	if !ok {
+		body = resp.Schema.Synthesize(s.swagger.Definitions)
+	}
+
+	if body == nil {
+		w.WriteHeader(statusCode(code))
+		return
+	}
+
+	w.Header().Set("Content-Type", mime)
+	w.WriteHeader(statusCode(code))
+	if str, ok := body.(string); ok && !strings.Contains(mime, "json") {
+		w.Write([]byte(str))
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
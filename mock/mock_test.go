@@ -0,0 +1,108 @@
+package mock
+
+import (
+	"encoding/json"
+	"github.com/peak6/arlong/schema"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testSwagger() *schema.Swagger {
+	swagger := schema.New()
+	swagger.BasePath = "/api"
+	swagger.Definitions["User"] = &schema.Schema{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]*schema.Schema{
+			"id":   {Type: "integer"},
+			"name": {Type: "string"},
+		},
+	}
+	swagger.Paths["/users/{id}"] = &schema.Path{
+		GET: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "id", In: schema.PATH, Type: "integer", Required: true},
+				{Name: "fields", In: schema.QUERY, Type: "string", Enum: []string{"all", "short"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"200": {Schema: &schema.Schema{Ref: "#/definitions/User"}},
+				"404": {Examples: map[string]interface{}{schema.MIME_JSON: map[string]interface{}{"message": "not found"}}},
+			},
+		},
+	}
+	swagger.Paths["/users/me"] = &schema.Path{
+		GET: &schema.Operation{
+			Responses: map[string]*schema.Responses{
+				"200": {Examples: map[string]interface{}{schema.MIME_JSON: map[string]interface{}{"id": 0, "name": "me"}}},
+			},
+		},
+	}
+	swagger.Paths["/users"] = &schema.Path{
+		POST: &schema.Operation{
+			Parameters: []*schema.Parameter{
+				{Name: "body", In: "body", Required: true, Schema: &schema.Schema{Ref: "#/definitions/User"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"201": {Schema: &schema.Schema{Ref: "#/definitions/User"}},
+			},
+		},
+	}
+
+	return swagger
+}
+
+func serve(method, url, body string, header map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	for key, val := range header {
+		req.Header.Set(key, val)
+	}
+
+	w := httptest.NewRecorder()
+	New(testSwagger()).ServeHTTP(w, req)
+
+	result := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+
+	return w, result
+}
+
+func TestMock(t *testing.T) {
+	w, body := serve("GET", "/api/users/7", "", nil)
+	if w.Code != http.StatusOK || body["name"] != "string" {
+		t.Fatalf("Expected a synthesized user, got %d %v", w.Code, body)
+	}
+
+	w, body = serve("GET", "/api/users/me", "", nil)
+	if w.Code != http.StatusOK || body["name"] != "me" {
+		t.Fatalf("Expected the /users/me example, got %d %v", w.Code, body)
+	}
+
+	w, body = serve("GET", "/api/users/7", "", map[string]string{"Prefer": "code=404"})
+	if w.Code != http.StatusNotFound || body["message"] != "not found" {
+		t.Fatalf("Expected the 404 example, got %d %v", w.Code, body)
+	}
+
+	w, _ = serve("GET", "/api/users/abc?fields=none", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for invalid params, got %d", w.Code)
+	}
+
+	w, _ = serve("POST", "/api/users", `{"id": 1}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a body without name, got %d", w.Code)
+	}
+
+	w, _ = serve("POST", "/api/users", `{"id": 1, "name": "jane"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", w.Code)
+	}
+
+	if w, _ = serve("DELETE", "/api/users", "", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405, got %d", w.Code)
+	}
+	if w, _ = serve("GET", "/api/orders", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", w.Code)
+	}
+}
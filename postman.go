@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/postman"
+	"io/ioutil"
+	"os"
+)
+
+var postmanCommand = cli.Command{
+	Name:  "postman",
+	Usage: "Export the spec as a Postman v2.1 collection",
+	Flags: append([]cli.Flag{
+		specFlag,
+
+		cli.StringFlag{
+			Name:  "out, o",
+			Value: "collection.json",
+			Usage: "Collection file",
+		},
+
+		cli.StringFlag{
+			Name:  "env",
+			Value: "",
+			Usage: "Also write an environment file with the collection variables",
+		},
+	}, parserFlags...),
+	Action: func(c *cli.Context) {
+		swagger, err := loadSwagger(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		collection := postman.Convert(swagger)
+		if err := writeJSON(c.String("out"), collection); err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		if env := c.String("env"); env != "" {
+			if err := writeJSON(env, postman.NewEnvironment(collection.Info.Name, collection)); err != nil {
+				os.Stderr.WriteString(err.Error())
+			}
+		}
+	},
+}
+
+func writeJSON(file string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, b, 0644)
+}
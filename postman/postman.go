@@ -0,0 +1,384 @@
+// Package postman converts a Swagger document into a Postman v2.1
+// collection, which Insomnia imports as well.
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"sort"
+	"strings"
+)
+
+const SchemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type Collection struct {
+	Info     Info        `json:"info"`
+	Item     []*Item     `json:"item"`
+	Auth     *Auth       `json:"auth,omitempty"`
+	Variable []*Variable `json:"variable,omitempty"`
+}
+
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// Item is a request, or a folder when it has items of its own.
+type Item struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Item        []*Item  `json:"item,omitempty"`
+	Request     *Request `json:"request,omitempty"`
+}
+
+type Request struct {
+	Method      string `json:"method"`
+	Description string `json:"description,omitempty"`
+	Header      []*KV  `json:"header"`
+	Body        *Body  `json:"body,omitempty"`
+	URL         *URL   `json:"url"`
+	Auth        *Auth  `json:"auth,omitempty"`
+}
+
+type URL struct {
+	Raw      string      `json:"raw"`
+	Protocol string      `json:"protocol,omitempty"`
+	Host     []string    `json:"host"`
+	Path     []string    `json:"path"`
+	Query    []*KV       `json:"query,omitempty"`
+	Variable []*Variable `json:"variable,omitempty"`
+}
+
+// KV is a header, query parameter, form field or auth attribute.
+type KV struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+type Body struct {
+	Mode       string       `json:"mode"`
+	Raw        string       `json:"raw,omitempty"`
+	URLEncoded []*KV        `json:"urlencoded,omitempty"`
+	FormData   []*KV        `json:"formdata,omitempty"`
+	Options    *BodyOptions `json:"options,omitempty"`
+}
+
+type BodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type Auth struct {
+	Type   string `json:"type"`
+	APIKey []*KV  `json:"apikey,omitempty"`
+	Basic  []*KV  `json:"basic,omitempty"`
+	OAuth2 []*KV  `json:"oauth2,omitempty"`
+}
+
+type Variable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Environment is a Postman environment file setting the variables a
+// collection uses.
+type Environment struct {
+	Name   string              `json:"name"`
+	Values []*EnvironmentValue `json:"values"`
+}
+
+type EnvironmentValue struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled bool   `json:"enabled"`
+}
+
+type converter struct {
+	swagger   *schema.Swagger
+	validator *middleware.Validator
+	variables []*Variable
+	seen      map[string]bool
+}
+
+// Convert builds a collection with a folder per tag. The scheme, host and
+// base path are the {{scheme}}, {{host}} and {{basePath}} variables, and
+// credentials are variables named after their security definition.
+func Convert(swagger *schema.Swagger) *Collection {
+	c := &converter{swagger: swagger, validator: middleware.New(swagger), seen: make(map[string]bool)}
+
+	scheme := "https"
+	if len(swagger.Schemes) > 0 {
+		scheme = swagger.Schemes[0]
+	}
+	host := swagger.Host
+	if host == "" {
+		host = "localhost"
+	}
+	c.variable("scheme", scheme, "")
+	c.variable("host", host, "")
+	if c.basePath() != "" {
+		c.variable("basePath", c.basePath(), "")
+	}
+
+	collection := &Collection{
+		Info: Info{Name: swagger.Info.Title, Description: swagger.Info.Description, Schema: SchemaURL},
+		Item: []*Item{},
+		Auth: c.auth(swagger.Security),
+	}
+	if collection.Info.Name == "" {
+		collection.Info.Name = "API"
+	}
+
+	folders := make(map[string]*Item)
+	order := []string{}
+	for _, tag := range swagger.Tags {
+		folders[tag.Name] = &Item{Name: tag.Name, Description: tag.Description}
+		order = append(order, tag.Name)
+	}
+
+	routes := append([]*middleware.Route{}, c.validator.Router().Routes()...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Template != routes[j].Template {
+			return routes[i].Template < routes[j].Template
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	extra := []string{}
+	for _, rt := range routes {
+		item := c.item(rt)
+		if len(rt.Operation.Tags) == 0 {
+			collection.Item = append(collection.Item, item)
+			continue
+		}
+		for _, tag := range rt.Operation.Tags {
+			if folders[tag] == nil {
+				folders[tag] = &Item{Name: tag}
+				extra = append(extra, tag)
+			}
+			folders[tag].Item = append(folders[tag].Item, item)
+		}
+	}
+
+	sort.Strings(extra)
+	tagged := []*Item{}
+	for _, name := range append(order, extra...) {
+		if folder := folders[name]; len(folder.Item) > 0 {
+			tagged = append(tagged, folder)
+		}
+	}
+	collection.Item = append(tagged, collection.Item...)
+	collection.Variable = c.variables
+
+	return collection
+}
+
+// NewEnvironment returns an environment with the values of the variables
+// of a collection.
+func NewEnvironment(name string, collection *Collection) *Environment {
+	env := &Environment{Name: name, Values: []*EnvironmentValue{}}
+	for _, v := range collection.Variable {
+		env.Values = append(env.Values, &EnvironmentValue{Key: v.Key, Value: v.Value, Enabled: true})
+	}
+
+	return env
+}
+
+func (c *converter) basePath() string {
+	return strings.Trim(c.swagger.BasePath, "/")
+}
+
+func (c *converter) variable(key, value, description string) {
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.variables = append(c.variables, &Variable{Key: key, Value: value, Type: "string", Description: description})
+}
+
+// auth returns the auth block for the first security requirement. An
+// empty list of requirements turns auth off.
+func (c *converter) auth(requirements []map[string][]string) *Auth {
+	if requirements == nil {
+		return nil
+	}
+	if len(requirements) == 0 || len(requirements[0]) == 0 {
+		return &Auth{Type: "noauth"}
+	}
+
+	names := []string{}
+	for name := range requirements[0] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := c.swagger.SecurityDefinitions[name]
+		if def == nil {
+			continue
+		}
+
+		switch def.Type {
+		case "apiKey":
+			c.variable(name, "", def.Description)
+			return &Auth{Type: "apikey", APIKey: []*KV{
+				{Key: "key", Value: def.Name, Type: "string"},
+				{Key: "value", Value: "{{" + name + "}}", Type: "string"},
+				{Key: "in", Value: def.In, Type: "string"},
+			}}
+		case "basic":
+			c.variable("username", "", "")
+			c.variable("password", "", "")
+			return &Auth{Type: "basic", Basic: []*KV{
+				{Key: "username", Value: "{{username}}", Type: "string"},
+				{Key: "password", Value: "{{password}}", Type: "string"},
+			}}
+		case "oauth2":
+			c.variable("accessToken", "", def.Description)
+			attrs := []*KV{
+				{Key: "accessToken", Value: "{{accessToken}}", Type: "string"},
+				{Key: "addTokenTo", Value: "header", Type: "string"},
+			}
+			if def.AuthorizationUrl != "" {
+				attrs = append(attrs, &KV{Key: "authUrl", Value: def.AuthorizationUrl, Type: "string"})
+			}
+			if def.TokenUrl != "" {
+				attrs = append(attrs, &KV{Key: "accessTokenUrl", Value: def.TokenUrl, Type: "string"})
+			}
+			if grant := grantType(def.Flow); grant != "" {
+				attrs = append(attrs, &KV{Key: "grant_type", Value: grant, Type: "string"})
+			}
+			scopes := requirements[0][name]
+			if len(scopes) > 0 {
+				attrs = append(attrs, &KV{Key: "scope", Value: strings.Join(scopes, " "), Type: "string"})
+			}
+			return &Auth{Type: "oauth2", OAuth2: attrs}
+		}
+	}
+
+	return nil
+}
+
+func grantType(flow string) string {
+	switch flow {
+	case "implicit":
+		return "implicit"
+	case "password":
+		return "password_credentials"
+	case "application":
+		return "client_credentials"
+	case "accessCode":
+		return "authorization_code"
+	}
+
+	return ""
+}
+
+// placeholder is the value of a parameter in a request: its example, or
+// its type in angle brackets.
+func placeholder(p *schema.Parameter) string {
+	if p.Example != nil {
+		return fmt.Sprint(p.Example)
+	}
+	if p.Default != nil {
+		return fmt.Sprint(p.Default)
+	}
+	if len(p.Enum) > 0 {
+		return p.Enum[0]
+	}
+	if p.Type == "array" && p.Items != nil {
+		return "<" + p.Items.Type + ">,<" + p.Items.Type + ">"
+	}
+
+	return "<" + p.Type + ">"
+}
+
+func (c *converter) item(rt *middleware.Route) *Item {
+	op := rt.Operation
+	name := op.Summary
+	if name == "" {
+		name = op.OperationId
+	}
+	if name == "" {
+		name = rt.Method + " " + rt.Template
+	}
+
+	req := &Request{
+		Method:      rt.Method,
+		Description: op.Description,
+		Header:      []*KV{},
+		URL:         &URL{Protocol: "{{scheme}}", Host: []string{"{{host}}"}},
+	}
+	if op.Security != nil {
+		req.Auth = c.auth(op.Security)
+	}
+
+	if c.basePath() != "" {
+		req.URL.Path = append(req.URL.Path, "{{basePath}}")
+	}
+	for _, segment := range strings.Split(strings.Trim(rt.Template, "/"), "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = ":" + strings.Trim(segment, "{}")
+		}
+		if segment != "" {
+			req.URL.Path = append(req.URL.Path, segment)
+		}
+	}
+
+	form := []*KV{}
+	multipart := false
+	for _, p := range c.validator.Parameters(rt) {
+		switch p.In {
+		case schema.PATH:
+			req.URL.Variable = append(req.URL.Variable, &Variable{Key: p.Name, Value: placeholder(p), Description: p.Description})
+		case schema.QUERY:
+			req.URL.Query = append(req.URL.Query, &KV{Key: p.Name, Value: placeholder(p), Description: p.Description, Disabled: !p.Required})
+		case schema.HEADER:
+			req.Header = append(req.Header, &KV{Key: p.Name, Value: placeholder(p), Description: p.Description, Disabled: !p.Required})
+		case schema.FORMDATA:
+			kv := &KV{Key: p.Name, Value: placeholder(p), Type: "text", Description: p.Description}
+			if p.Type == "file" {
+				kv.Type, kv.Value, multipart = "file", "", true
+			}
+			form = append(form, kv)
+		case "body":
+			b, _ := json.MarshalIndent(p.Synthesize(c.swagger.Definitions), "", "  ")
+			req.Body = &Body{Mode: "raw", Raw: string(b), Options: &BodyOptions{}}
+			req.Body.Options.Raw.Language = "json"
+			req.Header = append(req.Header, &KV{Key: "Content-Type", Value: schema.MIME_JSON})
+		}
+	}
+
+	if len(form) > 0 {
+		for _, mime := range op.Consumes {
+			multipart = multipart || mime == schema.MIME_MULTIPART
+		}
+		if multipart {
+			req.Body = &Body{Mode: "formdata", FormData: form}
+		} else {
+			req.Body = &Body{Mode: "urlencoded", URLEncoded: form}
+		}
+	}
+
+	req.URL.Raw = "{{scheme}}://{{host}}/" + strings.Join(req.URL.Path, "/")
+	query := []string{}
+	for _, q := range req.URL.Query {
+		if !q.Disabled {
+			query = append(query, q.Key+"="+q.Value)
+		}
+	}
+	if len(query) > 0 {
+		req.URL.Raw += "?" + strings.Join(query, "&")
+	}
+
+	return &Item{Name: name, Description: op.Description, Request: req}
+}
@@ -0,0 +1,71 @@
+package postman
+
+import (
+	"github.com/peak6/arlong/schema"
+	"reflect"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	swagger := schema.New()
+	swagger.Info.Title = "Pets"
+	swagger.Host = "api.example.com"
+	swagger.BasePath = "/v1"
+	swagger.Tags = []*schema.Tag{{Name: "pets"}}
+	swagger.SecurityDefinitions["token"] = &schema.SecurityDefinitions{Type: "apiKey", In: "header", Name: "X-Token"}
+	swagger.SecurityDefinitions["login"] = &schema.SecurityDefinitions{Type: "basic"}
+	swagger.Security = []map[string][]string{{"token": {}}}
+	swagger.Definitions["Pet"] = &schema.Schema{Type: "object", Properties: map[string]*schema.Schema{"name": {Type: "string"}}}
+	swagger.Paths["/pets/{id}"] = &schema.Path{
+		PUT: &schema.Operation{
+			Tags:     []string{"pets"},
+			Summary:  "Update a pet",
+			Security: []map[string][]string{{"login": {}}},
+			Parameters: []*schema.Parameter{
+				{Name: "id", In: schema.PATH, Type: "integer", Required: true, Example: 7},
+				{Name: "dryRun", In: schema.QUERY, Type: "boolean"},
+				{Name: "X-Request-Id", In: schema.HEADER, Type: "string", Required: true},
+				{Name: "body", In: "body", Schema: &schema.Schema{Ref: "#/definitions/Pet"}},
+			},
+		},
+	}
+	swagger.Paths["/health"] = &schema.Path{GET: &schema.Operation{}}
+
+	c := Convert(swagger)
+	if c.Info.Schema != SchemaURL || c.Auth.Type != "apikey" || c.Auth.APIKey[1].Value != "{{token}}" {
+		t.Errorf("unexpected collection %+v %+v", c.Info, c.Auth)
+	}
+
+	if len(c.Item) != 2 || c.Item[0].Name != "pets" || c.Item[1].Name != "GET /health" {
+		t.Fatalf("unexpected items %+v", c.Item)
+	}
+
+	req := c.Item[0].Item[0].Request
+	if req.URL.Raw != "{{scheme}}://{{host}}/{{basePath}}/pets/:id" {
+		t.Errorf("unexpected url %s", req.URL.Raw)
+	}
+	if !reflect.DeepEqual(req.URL.Path, []string{"{{basePath}}", "pets", ":id"}) || req.URL.Variable[0].Value != "7" {
+		t.Errorf("unexpected path %v %+v", req.URL.Path, req.URL.Variable[0])
+	}
+	if q := req.URL.Query[0]; q.Key != "dryRun" || q.Value != "<boolean>" || !q.Disabled {
+		t.Errorf("unexpected query %+v", q)
+	}
+	if h := req.Header[0]; h.Key != "X-Request-Id" || h.Value != "<string>" || h.Disabled {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if req.Body.Mode != "raw" || req.Body.Raw != "{\n  \"name\": \"string\"\n}" {
+		t.Errorf("unexpected body %+v", req.Body)
+	}
+	if req.Auth.Type != "basic" {
+		t.Errorf("unexpected auth %+v", req.Auth)
+	}
+
+	env := NewEnvironment("pets", c)
+	keys := []string{}
+	for _, v := range env.Values {
+		keys = append(keys, v.Key)
+	}
+	if !reflect.DeepEqual(keys, []string{"scheme", "host", "basePath", "token", "username", "password"}) {
+		t.Errorf("unexpected environment %v", keys)
+	}
+}
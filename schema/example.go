@@ -0,0 +1,279 @@
+package schema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatExamples are sample values for string formats.
+var formatExamples = map[string]string{
+	"date-time": "2024-01-15T09:30:00Z",
+	"datetime":  "2024-01-15T09:30:00Z",
+	"date":      "2024-01-15",
+	"email":     "jane.doe@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"byte":      "ZXhhbXBsZQ==",
+	"binary":    "example",
+	"password":  "********",
+	"decimal":   "12.50",
+}
+
+// Synthesize returns an example value for s. Explicit examples win, $refs
+// are resolved through definitions, and recursive references end in nil.
+func (s *Schema) Synthesize(definitions map[string]*Schema) interface{} {
+	return s.synthesize(definitions, map[string]bool{})
+}
+
+func (s *Schema) synthesize(definitions map[string]*Schema, visiting map[string]bool) interface{} {
+	if s == nil {
+		return nil
+	}
+
+	if s.Example != nil {
+		return s.Example
+	}
+
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		def, ok := definitions[name]
+		if !ok || visiting[name] {
+			return nil
+		}
+
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		return def.synthesize(definitions, visiting)
+	}
+
+	if len(s.Enum) > 0 {
+		return enumExample(s.Type, s.Enum[0])
+	}
+
+	if len(s.AllOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, sub := range s.AllOf {
+			if obj, ok := sub.synthesize(definitions, visiting).(map[string]interface{}); ok {
+				for key, val := range obj {
+					merged[key] = val
+				}
+			}
+		}
+		for key, val := range s.objectExample(definitions, visiting) {
+			merged[key] = val
+		}
+		return merged
+	}
+
+	switch s.Type {
+	case "string":
+		return s.stringExample()
+	case "integer":
+		return s.intExample()
+	case "number":
+		return s.numberExample()
+	case "boolean":
+		return true
+	case "array":
+		count := 1
+		if s.MinItems > count {
+			count = s.MinItems
+		}
+		items := make([]interface{}, 0, count)
+		item := s.Items.synthesize(definitions, visiting)
+		if item == nil {
+			return items
+		}
+		for i := 0; i < count; i++ {
+			items = append(items, item)
+		}
+		return items
+	case "object", "":
+		if len(s.Properties) == 0 && s.AdditionalProperties == nil && s.Type == "" {
+			return map[string]interface{}{}
+		}
+		return s.objectExample(definitions, visiting)
+	}
+
+	return nil
+}
+
+func (s *Schema) objectExample(definitions map[string]*Schema, visiting map[string]bool) map[string]interface{} {
+	obj := map[string]interface{}{}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if val := s.Properties[name].synthesize(definitions, visiting); val != nil {
+			obj[name] = val
+		}
+	}
+
+	if s.AdditionalProperties != nil {
+		if val := s.AdditionalProperties.synthesize(definitions, visiting); val != nil {
+			obj["key"] = val
+		}
+	}
+
+	return obj
+}
+
+// stringExample returns the sample of the format, which is kept whole
+// since a shorter or longer one would not be valid, or a string that fits
+// the length constraints.
+func (s *Schema) stringExample() string {
+	if val, ok := formatExamples[s.Format]; ok {
+		return val
+	}
+
+	val := "string"
+	if s.MaxLength > 0 && len(val) > s.MaxLength {
+		val = val[:s.MaxLength]
+	}
+	for len(val) < s.MinLength {
+		val += "x"
+	}
+
+	return val
+}
+
+func (s *Schema) intExample() int {
+	val := 1
+	if s.Minimum > val {
+		val = s.Minimum
+	}
+	if s.Maximum != 0 && val > s.Maximum {
+		val = s.Maximum
+	}
+
+	return val
+}
+
+// numberExample returns a fractional value within the range.
+func (s *Schema) numberExample() float64 {
+	val := float64(s.intExample()) + 0.5
+	if s.Maximum != 0 && val > float64(s.Maximum) {
+		val = float64(s.Maximum)
+	}
+
+	return val
+}
+
+func enumExample(typ, val string) interface{} {
+	switch typ {
+	case "integer":
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+
+	return val
+}
+
+// Synthesize returns an example value for a parameter: its example, its
+// default, or one built from its type. Body parameters use their schema.
+func (p *Parameter) Synthesize(definitions map[string]*Schema) interface{} {
+	switch {
+	case p.Example != nil:
+		return p.Example
+	case p.Default != nil:
+		return p.Default
+	case p.Schema != nil:
+		return p.Schema.Synthesize(definitions)
+	}
+
+	s := &Schema{
+		Type:      p.Type,
+		Format:    p.Format,
+		Enum:      p.Enum,
+		Minimum:   p.Minimum,
+		Maximum:   p.Maximum,
+		MinLength: p.MinLength,
+		MaxLength: p.MaxLength,
+		MinItems:  p.MinItems,
+	}
+	if item := p.Items; item != nil {
+		s.Items = &Schema{
+			Type:      item.Type,
+			Format:    item.Format,
+			Enum:      item.Enum,
+			Minimum:   item.Minimum,
+			Maximum:   item.Maximum,
+			MinLength: item.MinLength,
+			MaxLength: item.MaxLength,
+		}
+	}
+
+	return s.Synthesize(definitions)
+}
+
+// FillExamples sets an example on every definition and an
+// application/json example on every response with a schema, unless one
+// was given explicitly.
+func (s *Swagger) FillExamples() {
+	names := make([]string, 0, len(s.Definitions))
+	for name := range s.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if def := s.Definitions[name]; def.Example == nil {
+			def.Example = (&Schema{Ref: "#/definitions/" + name}).Synthesize(s.Definitions)
+		}
+	}
+
+	for _, resp := range s.Responses {
+		s.fillResponseExample(resp, s.Produces)
+	}
+
+	for _, path := range s.Paths {
+		for _, op := range path.Operations() {
+			produces := op.Produces
+			if len(produces) == 0 {
+				produces = s.Produces
+			}
+			for _, resp := range op.Responses {
+				s.fillResponseExample(resp, produces)
+			}
+		}
+	}
+}
+
+func (s *Swagger) fillResponseExample(resp *Responses, produces []string) {
+	if resp.Schema == nil || len(resp.Examples) > 0 {
+		return
+	}
+
+	mime := MIME_JSON
+	for _, produce := range produces {
+		if strings.Contains(produce, "json") {
+			mime = produce
+			break
+		}
+	}
+
+	val := resp.Schema.Synthesize(s.Definitions)
+	if val == nil {
+		return
+	}
+
+	resp.Examples = map[string]interface{}{mime: val}
+}
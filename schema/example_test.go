@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSynthesize(t *testing.T) {
+	definitions := map[string]*Schema{
+		"User": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"id":      {Type: "string", Format: "uuid"},
+				"email":   {Type: "string", Format: "email"},
+				"age":     {Type: "integer", Minimum: 18},
+				"role":    {Type: "string", Enum: []string{"admin", "member"}},
+				"code":    {Type: "string", MinLength: 8},
+				"parent":  {Ref: "#/definitions/User"},
+				"created": {Type: "string", Format: "date-time", Example: "2015-06-01T00:00:00Z"},
+			},
+		},
+	}
+
+	val := (&Schema{Type: "array", Items: &Schema{Ref: "#/definitions/User"}}).Synthesize(definitions)
+	expected := []interface{}{
+		map[string]interface{}{
+			"id":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+			"email":   "jane.doe@example.com",
+			"age":     18,
+			"role":    "admin",
+			"code":    "stringxx",
+			"created": "2015-06-01T00:00:00Z",
+		},
+	}
+
+	if !reflect.DeepEqual(val, expected) {
+		t.Errorf("unexpected example %#v", val)
+	}
+}
+
+func TestFillExamples(t *testing.T) {
+	swagger := New()
+	swagger.Definitions["Error"] = &Schema{Type: "object", Properties: map[string]*Schema{"code": {Type: "integer"}}}
+	swagger.Paths["/users"] = &Path{GET: &Operation{
+		Produces: []string{MIME_JSON},
+		Responses: map[string]*Responses{
+			"404": {Schema: &Schema{Ref: "#/definitions/Error"}},
+			"200": {Schema: &Schema{Type: "string"}, Examples: map[string]interface{}{MIME_TEXT: "ok"}},
+		},
+	}}
+
+	swagger.FillExamples()
+
+	if !reflect.DeepEqual(swagger.Definitions["Error"].Example, map[string]interface{}{"code": 1}) {
+		t.Errorf("unexpected definition example %#v", swagger.Definitions["Error"].Example)
+	}
+	if ex := swagger.Paths["/users"].GET.Responses["404"].Examples[MIME_JSON]; ex == nil {
+		t.Error("missing response example")
+	}
+	if ex := swagger.Paths["/users"].GET.Responses["200"].Examples; len(ex) != 1 || ex[MIME_TEXT] != "ok" {
+		t.Errorf("explicit response example was replaced: %v", ex)
+	}
+}
+
+func TestSynthesizeIsValid(t *testing.T) {
+	for _, s := range []*Schema{
+		{Type: "number", Maximum: 1},
+		{Type: "number", Minimum: 3, Maximum: 3},
+		{Type: "number", Minimum: 10, Maximum: 20},
+		{Type: "string", MaxLength: 3},
+	} {
+		val := s.Synthesize(nil)
+		if violations := s.Validate(val, nil); len(violations) > 0 {
+			t.Errorf("%#v: example %v is invalid: %v", s, val, violations)
+		}
+	}
+
+	if val := (&Schema{Type: "number", Maximum: 1}).Synthesize(nil); val != float64(1) {
+		t.Errorf("expected the maximum, got %v", val)
+	}
+	if val := (&Schema{Type: "string", Format: "date-time", MaxLength: 10}).Synthesize(nil); val != "2024-01-15T09:30:00Z" {
+		t.Errorf("expected the whole date-time sample, got %v", val)
+	}
+}
@@ -54,6 +54,8 @@ type Swagger struct {
 	SecurityDefinitions map[string]*SecurityDefinitions `json:"securityDefinitions,omitempty"`
 	Parameters          map[string]*Parameter           `json:"parameters,omitempty"`
 	Responses           map[string]*Responses           `json:"responses,omitempty"`
+	Tags                []*Tag                          `json:"tags,omitempty"`
+	ExternalDocs        *ExternalDocs                   `json:"externalDocs,omitempty"`
 }
 
 type Info struct {
@@ -76,6 +78,17 @@ type License struct {
 	URL  string `json:"url,omitempty"`
 }
 
+type Tag struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description,omitempty"`
+	ExternalDocs *ExternalDocs `json:"externalDocs,omitempty"`
+}
+
+type ExternalDocs struct {
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
+
 type Path struct {
 	Route      string      `json:"-"`
 	Ref        string      `json:"$ref,omitempty"`
@@ -89,39 +102,71 @@ type Path struct {
 	Parameters []Parameter `json:"parameters,omitempty"`
 }
 
+// Operations returns the operations defined on the path.
+func (p *Path) Operations() []*Operation {
+	ops := []*Operation{}
+	for _, op := range []*Operation{p.GET, p.PUT, p.POST, p.DELETE, p.OPTIONS, p.HEAD, p.PATCH} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+
+	return ops
+}
+
 type Operation struct {
-	Tags        []string              `json:"tags,omitempty"`
-	Summary     string                `json:"summary,omitempty"`
-	Description string                `json:"description,omitempty"`
-	OperationId string                `json:"operationId,omitempty"`
-	Consumes    []string              `json:"consumes,omitempty"`
-	Produces    []string              `json:"produces,omitempty"`
-	Parameters  []*Parameter          `json:"parameters,omitempty"`
-	Responses   map[string]*Responses `json:"responses,omitempty"`
-	Schemes     []string              `json:"schemes,omitempty"`
-	Deprecated  bool                  `json:"deprecated,omitempty"`
-	Security    []map[string][]string `json:"security,omitempty"`
+	Tags         []string              `json:"tags,omitempty"`
+	Summary      string                `json:"summary,omitempty"`
+	Description  string                `json:"description,omitempty"`
+	ExternalDocs *ExternalDocs         `json:"externalDocs,omitempty"`
+	OperationId  string                `json:"operationId,omitempty"`
+	Consumes     []string              `json:"consumes,omitempty"`
+	Produces     []string              `json:"produces,omitempty"`
+	Parameters   []*Parameter          `json:"parameters,omitempty"`
+	Responses    map[string]*Responses `json:"responses,omitempty"`
+	Schemes      []string              `json:"schemes,omitempty"`
+	Deprecated   bool                  `json:"deprecated,omitempty"`
+	Security     []map[string][]string `json:"security,omitempty"`
 }
 
 type Parameter struct {
-	Ref             string      `json:"$ref,omitempty"`
-	Name            string      `json:"name,omitempty"`
-	In              string      `json:"in,omitempty"`
-	Description     string      `json:"description,omitempty"`
-	Required        bool        `json:"required,omitempty"`
-	Schema          *Schema     `json:"schema,omitempty"`
-	Type            string      `json:"type,omitempty"`
-	Format          string      `json:"format,omitempty"`
-	AllowEmptyValue bool        `json:"allowEmptyValue,omitempty"`
-	Items           *Items      `json:"items,omitempty"`
-	Default         interface{} `json:"default,omitempty"`
-	Maximum         int         `json:"maximum,omitempty"`
-	Minimum         int         `json:"minimum,omitempty"`
-	MaxLength       int         `json:"maxLength,omitempty"`
-	MinLength       int         `json:"minLength,omitempty"`
-	MaxItems        int         `json:"maxItems,omitempty"`
-	MinItems        int         `json:"minItems,omitempty"`
-	Enum            []string    `json:"enum,omitempty"`
+	Ref              string      `json:"$ref,omitempty"`
+	Name             string      `json:"name,omitempty"`
+	In               string      `json:"in,omitempty"`
+	Description      string      `json:"description,omitempty"`
+	Required         bool        `json:"required,omitempty"`
+	Schema           *Schema     `json:"schema,omitempty"`
+	Type             string      `json:"type,omitempty"`
+	Format           string      `json:"format,omitempty"`
+	AllowEmptyValue  bool        `json:"allowEmptyValue,omitempty"`
+	Items            *Items      `json:"items,omitempty"`
+	CollectionFormat string      `json:"collectionFormat,omitempty"`
+	Default          interface{} `json:"default,omitempty"`
+	Maximum          int         `json:"maximum,omitempty"`
+	Minimum          int         `json:"minimum,omitempty"`
+	MaxLength        int         `json:"maxLength,omitempty"`
+	MinLength        int         `json:"minLength,omitempty"`
+	MaxItems         int         `json:"maxItems,omitempty"`
+	MinItems         int         `json:"minItems,omitempty"`
+	Enum             []string    `json:"enum,omitempty"`
+	Example          interface{} `json:"x-example,omitempty"`
+}
+
+// Separator returns what separates the values of an array parameter in
+// one string, or "" for "multi" arrays whose values are repeated instead.
+func (p *Parameter) Separator() string {
+	switch p.CollectionFormat {
+	case "ssv":
+		return " "
+	case "tsv":
+		return "\t"
+	case "pipes":
+		return "|"
+	case "multi":
+		return ""
+	}
+
+	return ","
 }
 
 type Schema struct {
@@ -136,6 +181,15 @@ type Schema struct {
 	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
 	RawRefName           string             `json:"-"`
 	Enum                 []string           `json:"enum,omitempty"`
+	ExternalDocs         *ExternalDocs      `json:"externalDocs,omitempty"`
+	Nullable             bool               `json:"x-nullable,omitempty"`
+	Maximum              int                `json:"maximum,omitempty"`
+	Minimum              int                `json:"minimum,omitempty"`
+	MaxLength            int                `json:"maxLength,omitempty"`
+	MinLength            int                `json:"minLength,omitempty"`
+	MaxItems             int                `json:"maxItems,omitempty"`
+	MinItems             int                `json:"minItems,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
 }
 
 type Items struct {
@@ -152,10 +206,11 @@ type Items struct {
 }
 
 type Responses struct {
-	Ref         string             `json:"$ref,omitempty"`
-	Description string             `json:"description"`
-	Schema      *Schema            `json:"schema,omitempty"`
-	Headers     map[string]*Header `json:"headers,omitempty"`
+	Ref         string                 `json:"$ref,omitempty"`
+	Description string                 `json:"description"`
+	Schema      *Schema                `json:"schema,omitempty"`
+	Headers     map[string]*Header     `json:"headers,omitempty"`
+	Examples    map[string]interface{} `json:"examples,omitempty"`
 }
 
 type Field struct {
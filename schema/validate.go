@@ -0,0 +1,322 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Resolver finds the schema a $ref points to.
+type Resolver interface {
+	Resolve(ref string) (*Schema, bool)
+}
+
+// Definitions resolves "#/definitions/..." refs from a map of definitions.
+type Definitions map[string]*Schema
+
+func (d Definitions) Resolve(ref string) (*Schema, bool) {
+	def, ok := d[strings.TrimPrefix(ref, "#/definitions/")]
+	return def, ok && def != nil
+}
+
+// Resolve finds the definition a "#/definitions/..." ref points to.
+func (s *Swagger) Resolve(ref string) (*Schema, bool) {
+	return Definitions(s.Definitions).Resolve(ref)
+}
+
+// Violation is a place where a value does not match its schema. Path is
+// the JSON pointer of the value, "" for the root.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	if v.Path == "" {
+		return v.Message
+	}
+
+	return v.Path + ": " + v.Message
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate checks a decoded JSON value against s and returns every
+// violation. $refs are looked up with resolver.
+func (s *Schema) Validate(value interface{}, resolver Resolver) []*Violation {
+	v := &validation{resolver: resolver, visiting: make(map[string]bool)}
+	v.validate(s, value, "")
+
+	return v.violations
+}
+
+type validation struct {
+	resolver   Resolver
+	visiting   map[string]bool
+	violations []*Violation
+}
+
+func (v *validation) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, &Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// pointerToken escapes a property name for a JSON pointer.
+func pointerToken(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+func (v *validation) validate(s *Schema, value interface{}, path string) {
+	if s == nil {
+		return
+	}
+
+	if value == nil && s.Nullable {
+		return
+	}
+
+	if s.Ref != "" {
+		// a ref that leads back to itself without consuming the value
+		// would never end
+		key := s.Ref + " " + path
+		if v.visiting[key] {
+			return
+		}
+
+		def, ok := (*Schema)(nil), false
+		if v.resolver != nil {
+			def, ok = v.resolver.Resolve(s.Ref)
+		}
+		if !ok {
+			v.fail(path, "unresolved $ref %s", s.Ref)
+			return
+		}
+
+		v.visiting[key] = true
+		v.validate(def, value, path)
+		delete(v.visiting, key)
+		return
+	}
+
+	for _, sub := range s.AllOf {
+		v.validate(sub, value, path)
+	}
+
+	typ := s.Type
+	if typ == "" && (len(s.Properties) > 0 || len(s.Required) > 0 || s.AdditionalProperties != nil) {
+		// definitions of structs have properties but no type
+		typ = "object"
+	}
+
+	if value == nil {
+		if typ != "" && !s.Nullable {
+			v.fail(path, "expected %s, got null", typ)
+		}
+		return
+	}
+
+	if n, ok := value.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			value = f
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		v.validateEnum(s, value, path)
+	}
+
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.fail(path, "expected a string, got %s", describe(value))
+			return
+		}
+		v.validateString(s, str, path)
+	case "integer":
+		f, ok := number(value)
+		if !ok || f != math.Trunc(f) {
+			v.fail(path, "expected an integer, got %s", describe(value))
+			return
+		}
+		v.validateNumber(s, f, path)
+	case "number":
+		f, ok := number(value)
+		if !ok {
+			v.fail(path, "expected a number, got %s", describe(value))
+			return
+		}
+		v.validateNumber(s, f, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(path, "expected a boolean, got %s", describe(value))
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, "expected an array, got %s", describe(value))
+			return
+		}
+		v.validateArray(s, items, path)
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, "expected an object, got %s", describe(value))
+			return
+		}
+		v.validateObject(s, obj, path)
+	}
+}
+
+func (v *validation) validateEnum(s *Schema, value interface{}, path string) {
+	str := fmt.Sprint(value)
+	if f, ok := number(value); ok {
+		str = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	for _, enum := range s.Enum {
+		if enum == str {
+			return
+		}
+	}
+
+	v.fail(path, "%s is not one of %s", describe(value), strings.Join(s.Enum, ", "))
+}
+
+func (v *validation) validateString(s *Schema, str, path string) {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength > 0 && length < s.MinLength {
+		v.fail(path, "expected at least %d characters, got %d", s.MinLength, length)
+	}
+	if s.MaxLength > 0 && length > s.MaxLength {
+		v.fail(path, "expected at most %d characters, got %d", s.MaxLength, length)
+	}
+
+	if !validFormat(s.Format, str) {
+		v.fail(path, "%q is not a valid %s", str, s.Format)
+	}
+}
+
+func validFormat(format, str string) bool {
+	var err error
+	switch format {
+	case "date-time", DATETIME:
+		_, err = time.Parse(time.RFC3339, str)
+	case DATE:
+		_, err = time.Parse("2006-01-02", str)
+	case "email":
+		_, err = mail.ParseAddress(str)
+	case "uuid":
+		return uuidPattern.MatchString(str)
+	case "uri", "url":
+		var u *url.URL
+		u, err = url.Parse(str)
+		return err == nil && u.Scheme != ""
+	case "ipv4":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() != nil
+	case "ipv6":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() == nil
+	case BYTE:
+		_, err = base64.StdEncoding.DecodeString(str)
+	}
+
+	return err == nil
+}
+
+func (v *validation) validateNumber(s *Schema, f float64, path string) {
+	switch s.Format {
+	case INT32:
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			v.fail(path, "%v overflows int32", f)
+		}
+	case INT64:
+		if f < math.MinInt64 || f > math.MaxInt64 {
+			v.fail(path, "%v overflows int64", f)
+		}
+	}
+
+	if s.Minimum != 0 && f < float64(s.Minimum) {
+		v.fail(path, "%v is less than %d", f, s.Minimum)
+	}
+	if s.Maximum != 0 && f > float64(s.Maximum) {
+		v.fail(path, "%v is greater than %d", f, s.Maximum)
+	}
+}
+
+func (v *validation) validateArray(s *Schema, items []interface{}, path string) {
+	if s.MinItems > 0 && len(items) < s.MinItems {
+		v.fail(path, "expected at least %d items, got %d", s.MinItems, len(items))
+	}
+	if s.MaxItems > 0 && len(items) > s.MaxItems {
+		v.fail(path, "expected at most %d items, got %d", s.MaxItems, len(items))
+	}
+
+	for i, item := range items {
+		v.validate(s.Items, item, path+"/"+strconv.Itoa(i))
+	}
+}
+
+func (v *validation) validateObject(s *Schema, obj map[string]interface{}, path string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path, "missing required property %s", name)
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if prop, ok := s.Properties[name]; ok {
+			v.validate(prop, obj[name], path+"/"+pointerToken(name))
+		} else if s.AdditionalProperties != nil {
+			v.validate(s.AdditionalProperties, obj[name], path+"/"+pointerToken(name))
+		}
+	}
+}
+
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+func describe(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(val)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	definitions := Definitions{
+		"Base": {
+			Type:     "object",
+			Required: []string{"id"},
+			Properties: map[string]*Schema{
+				"id": {Type: "string", Format: "uuid"},
+			},
+		},
+		"User": {
+			AllOf: []*Schema{
+				{Ref: "#/definitions/Base"},
+				{
+					Type:     "object",
+					Required: []string{"email"},
+					Properties: map[string]*Schema{
+						"email":  {Type: "string", Format: "email"},
+						"age":    {Type: "integer", Format: INT32, Minimum: 18},
+						"role":   {Type: "string", Enum: []string{"admin", "member"}},
+						"tags":   {Type: "array", MaxItems: 2, Items: &Schema{Type: "string", MinLength: 2}},
+						"labels": {Type: "object", AdditionalProperties: &Schema{Type: "integer"}},
+						"parent": {Ref: "#/definitions/User"},
+						"note":   {Type: "string", Nullable: true},
+					},
+				},
+			},
+		},
+	}
+
+	var value interface{}
+	json.Unmarshal([]byte(`{
+		"email": "nope",
+		"age": 12.5,
+		"role": "root",
+		"tags": ["a", "bb", "cc"],
+		"labels": {"a/b": "x"},
+		"parent": {"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "email": "jane@example.com"},
+		"note": null
+	}`), &value)
+
+	violations := (&Schema{Ref: "#/definitions/User"}).Validate(value, definitions)
+	messages := []string{}
+	for _, violation := range violations {
+		messages = append(messages, violation.Error())
+	}
+
+	expected := []string{
+		"missing required property id",
+		"/age: expected an integer, got 12.5",
+		`/email: "nope" is not a valid email`,
+		"/labels/a~1b: expected an integer, got \"x\"",
+		`/role: "root" is not one of admin, member`,
+		"/tags: expected at most 2 items, got 3",
+		"/tags/0: expected at least 2 characters, got 1",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("unexpected violations %#v", messages)
+	}
+
+	if violations := (&Schema{Ref: "#/definitions/Missing"}).Validate(map[string]interface{}{}, definitions); len(violations) != 1 {
+		t.Errorf("expected an unresolved $ref, got %v", violations)
+	}
+
+	if violations := (&Schema{Type: "integer"}).Validate(nil, nil); len(violations) != 1 {
+		t.Errorf("expected null to be rejected, got %v", violations)
+	}
+}
+
+func TestValidateNullableRef(t *testing.T) {
+	definitions := Definitions{"Owner": {Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}}}
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"owner":  {Ref: "#/definitions/Owner", Nullable: true},
+			"parent": {Ref: "#/definitions/Owner"},
+		},
+	}
+
+	var value interface{}
+	json.Unmarshal([]byte(`{"owner": null, "parent": null}`), &value)
+
+	violations := s.Validate(value, definitions)
+	if len(violations) != 1 || violations[0].Error() != "/parent: expected object, got null" {
+		t.Errorf("expected only the non-nullable ref to reject null, got %v", violations)
+	}
+}
+
+func TestValidateUntypedStruct(t *testing.T) {
+	definitions := Definitions{"Pet": {Required: []string{"name"}, Properties: map[string]*Schema{"name": {Type: "string"}}}}
+	s := &Schema{Ref: "#/definitions/Pet"}
+
+	for _, raw := range []string{`"hello"`, `[1]`, `42`, `null`} {
+		var value interface{}
+		json.Unmarshal([]byte(raw), &value)
+		if violations := s.Validate(value, definitions); len(violations) != 1 {
+			t.Errorf("expected %s to be rejected, got %v", raw, violations)
+		}
+	}
+
+	var value interface{}
+	json.Unmarshal([]byte(`{"name": "rex"}`), &value)
+	if violations := s.Validate(value, definitions); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/peak6/arlong/server"
+	"io/ioutil"
+	"os"
+)
+
+var serverCommand = cli.Command{
+	Name:  "server",
+	Usage: "Generate a Go ServerInterface, request and response types and a net/http handler",
+	Flags: append([]cli.Flag{
+		specFlag,
+
+		cli.StringFlag{
+			Name:  "package",
+			Value: "api",
+			Usage: "Package name of the generated file",
+		},
+
+		cli.StringFlag{
+			Name:  "out, o",
+			Value: "server.gen.go",
+			Usage: "Output file",
+		},
+	}, parserFlags...),
+	Action: func(c *cli.Context) {
+		swagger, err := loadSwagger(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		src, err := server.Generate(swagger, c.String("package"))
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		if err := ioutil.WriteFile(c.String("out"), src, 0644); err != nil {
+			os.Stderr.WriteString(err.Error())
+		}
+	},
+}
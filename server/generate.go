@@ -0,0 +1,398 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/peak6/arlong/goname"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Context is a definition rendered as a Go type.
+type Context struct {
+	Name string
+	// Type is set for definitions that are not objects, e.g. string enums.
+	Type       string
+	Embeds     []string
+	Properties []ContextProperty
+}
+
+// ContextProperty is a field of a generated struct.
+type ContextProperty struct {
+	Name     string
+	Field    string
+	Type     string
+	Required bool
+}
+
+// OperationContext is an operation rendered as a ServerInterface method,
+// its request struct and response helpers.
+type OperationContext struct {
+	Name      string
+	Key       string
+	Summary   string
+	Params    []ParamContext
+	Body      *ParamContext
+	Responses []ResponseContext
+}
+
+// ParamContext is a field of a generated request struct. Optional
+// parameters are pointers, except for slices.
+type ParamContext struct {
+	Name     string
+	Field    string
+	In       string
+	Type     string
+	Parse    string
+	Required bool
+	Pointer  bool
+}
+
+// ResponseContext is a typed helper building a Response for one status
+// code. Type is empty when the response has no body.
+type ResponseContext struct {
+	Code string
+	Func string
+	Type string
+}
+
+type fileContext struct {
+	Package    string
+	Swagger    string
+	Models     []Context
+	Operations []OperationContext
+}
+
+// parseFuncs maps parameter types to the runtime conversion reading them.
+var parseFuncs = map[string]string{
+	"string":    "String",
+	"int32":     "Int32",
+	"int64":     "Int64",
+	"float32":   "Float32",
+	"float64":   "Float64",
+	"bool":      "Bool",
+	"[]string":  "Strings",
+	"[]int32":   "Int32s",
+	"[]int64":   "Int64s",
+	"[]float32": "Float32s",
+	"[]float64": "Float64s",
+	"[]bool":    "Bools",
+}
+
+type generator struct {
+	swagger *schema.Swagger
+	names   map[string]string
+	// decls holds the package level identifiers of the file.
+	decls *goname.Scope
+}
+
+// modelNames gives each definition a Go name, the last segment of its key
+// unless two definitions share it.
+func (g *generator) modelNames() {
+	g.decls = goname.NewScope("ServerInterface", "Handler")
+	keys := make([]string, 0, len(g.swagger.Definitions))
+	for key := range g.swagger.Definitions {
+		keys = append(keys, key)
+	}
+	g.names = goname.Definitions(keys, g.decls)
+}
+
+func (g *generator) goType(s *schema.Schema) string {
+	if s == nil {
+		return "interface{}"
+	}
+
+	if s.Ref != "" {
+		if name, ok := g.names[strings.TrimPrefix(s.Ref, "#/definitions/")]; ok {
+			return name
+		}
+		return "interface{}"
+	}
+
+	return g.basicType(s.Type, s.Format, s.Items, s.AdditionalProperties)
+}
+
+func (g *generator) basicType(typ, format string, items, additional *schema.Schema) string {
+	switch typ {
+	case "string":
+		return "string"
+	case "integer":
+		if format == schema.INT32 {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if format == schema.FLOAT {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(items)
+	case "object":
+		if additional != nil {
+			return "map[string]" + g.goType(additional)
+		}
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+func (g *generator) paramType(param *schema.Parameter) string {
+	if param.Type == "array" && param.Items != nil {
+		return "[]" + g.basicType(param.Items.Type, param.Items.Format, nil, nil)
+	}
+
+	return g.basicType(param.Type, param.Format, nil, nil)
+}
+
+func (g *generator) model(key string, def *schema.Schema) Context {
+	ctx := Context{Name: g.names[key]}
+	object := def.Type == "object" || (def.Type == "" && (len(def.Properties) > 0 || len(def.AllOf) > 0))
+	if !object {
+		ctx.Type = g.goType(def)
+		return ctx
+	}
+
+	schemas := []*schema.Schema{def}
+	for _, sub := range def.AllOf {
+		if sub.Ref != "" {
+			ctx.Embeds = append(ctx.Embeds, g.goType(sub))
+		} else {
+			schemas = append(schemas, sub)
+		}
+	}
+
+	// embedded types are fields named after the type
+	fields := goname.NewScope(ctx.Embeds...)
+
+	for _, s := range schemas {
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			required := false
+			for _, r := range s.Required {
+				required = required || r == name
+			}
+			typ := g.goType(s.Properties[name])
+			if s.Properties[name].Nullable {
+				typ = "*" + typ
+			}
+			ctx.Properties = append(ctx.Properties, ContextProperty{
+				Name:     name,
+				Field:    fields.Unique(name),
+				Type:     typ,
+				Required: required,
+			})
+		}
+	}
+
+	return ctx
+}
+
+func operationName(rt *middleware.Route) string {
+	if rt.Operation.OperationId != "" {
+		return goname.Identifier(rt.Operation.OperationId)
+	}
+
+	return goname.Identifier(strings.ToLower(rt.Method) + " " + rt.Template)
+}
+
+func (g *generator) operation(v *middleware.Validator, rt *middleware.Route) OperationContext {
+	ctx := OperationContext{
+		Key:     rt.Method + " " + rt.Template,
+		Summary: rt.Operation.Summary,
+	}
+
+	codes := make([]string, 0, len(rt.Operation.Responses))
+	for code := range rt.Operation.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	// the method, its request struct and its response helpers must all be
+	// free, e.g. a definition may already be called GetPetRequest
+	decls := func(name string) []string {
+		names := []string{name, name + "Request"}
+		for _, code := range codes {
+			names = append(names, responseFunc(name, code))
+		}
+		return names
+	}
+	base := operationName(rt)
+	ctx.Name = base
+	for i := 2; g.decls.Taken(decls(ctx.Name)...); i++ {
+		ctx.Name = base + strconv.Itoa(i)
+	}
+	g.decls.Take(decls(ctx.Name)...)
+
+	params := v.Parameters(rt)
+	fields := goname.NewScope()
+	for _, param := range params {
+		if param.In == "body" {
+			fields.Take("Body")
+		}
+	}
+
+	for _, param := range params {
+		if param.In == "body" {
+			ctx.Body = &ParamContext{Name: param.Name, Field: "Body", In: param.In, Type: g.goType(param.Schema), Required: param.Required, Pointer: !param.Required}
+			continue
+		}
+
+		typ := g.paramType(param)
+		parse, ok := parseFuncs[typ]
+		if !ok {
+			typ, parse = "string", "String"
+		}
+		ctx.Params = append(ctx.Params, ParamContext{
+			Name:     param.Name,
+			Field:    fields.Unique(param.Name),
+			In:       param.In,
+			Type:     typ,
+			Parse:    parse,
+			Required: param.Required,
+			Pointer:  !param.Required && !strings.HasPrefix(typ, "[]"),
+		})
+	}
+
+	for _, code := range codes {
+		// "default" is not a status code, so it is looked up as one that
+		// is not declared
+		resp := v.Response(rt.Operation, statusCode(code))
+		r := ResponseContext{Code: code, Func: responseFunc(ctx.Name, code)}
+		if resp != nil && resp.Schema != nil {
+			r.Type = g.goType(resp.Schema)
+		}
+		ctx.Responses = append(ctx.Responses, r)
+	}
+
+	return ctx
+}
+
+// responseFunc returns the name of the helper building the response of an
+// operation for code.
+func responseFunc(operation, code string) string {
+	if code == "default" {
+		return operation + "Default"
+	}
+
+	return operation + code
+}
+
+func statusCode(code string) int {
+	status, _ := strconv.Atoi(code)
+	return status
+}
+
+// Generate returns the source of a Go file in package pkg with a model per
+// definition, a ServerInterface with one method per operation, a request
+// struct per operation, a response helper per declared status code, and a
+// Handler adapting a ServerInterface to net/http.
+func Generate(swagger *schema.Swagger, pkg string) ([]byte, error) {
+	g := &generator{swagger: swagger}
+	g.modelNames()
+
+	doc, err := json.Marshal(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := fileContext{Package: pkg, Swagger: strconv.Quote(string(doc))}
+
+	keys := make([]string, 0, len(swagger.Definitions))
+	for key := range swagger.Definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ctx.Models = append(ctx.Models, g.model(key, swagger.Definitions[key]))
+	}
+
+	// name the operations in a stable order, so a colliding name always
+	// gets the same suffix
+	v := middleware.New(swagger)
+	routes := append([]*middleware.Route{}, v.Router().Routes()...)
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Template+" "+routes[i].Method < routes[j].Template+" "+routes[j].Method
+	})
+	for _, rt := range routes {
+		ctx.Operations = append(ctx.Operations, g.operation(v, rt))
+	}
+	sort.Slice(ctx.Operations, func(i, j int) bool {
+		return ctx.Operations[i].Name < ctx.Operations[j].Name
+	})
+
+	buf := &bytes.Buffer{}
+	if err := serverTpl.Execute(buf, ctx); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+var serverTpl = template.Must(template.New("server").Parse(`// Code generated by arlong. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{if .Operations}}	"context"
+{{end}}	"net/http"
+
+	"github.com/peak6/arlong/server"
+)
+{{range .Models}}
+{{if .Type}}type {{.Name}} {{.Type}}
+{{else}}type {{.Name}} struct {
+{{range .Embeds}}	{{.}}
+{{end}}{{range .Properties}}	{{.Field}} {{.Type}} ` + "`" + `json:"{{.Name}}{{if not .Required}},omitempty{{end}}"` + "`" + `
+{{end}}}
+{{end}}{{end}}
+// ServerInterface has one method per operation of the document.
+type ServerInterface interface {
+{{range .Operations}}{{if .Summary}}	// {{.Summary}}
+{{end}}	{{.Name}}(ctx context.Context, req *{{.Name}}Request) (*server.Response, error)
+{{end}}}
+{{range $op := .Operations}}
+// {{.Name}}Request holds the parameters of {{.Key}}.
+type {{.Name}}Request struct {
+{{range .Params}}	{{.Field}} {{if .Pointer}}*{{end}}{{.Type}}
+{{end}}{{with .Body}}	Body {{if .Pointer}}*{{end}}{{.Type}}
+{{end}}}
+{{range .Responses}}
+func {{.Func}}({{if eq .Code "default"}}code int{{if .Type}}, {{end}}{{end}}{{if .Type}}body {{.Type}}{{end}}) *server.Response {
+	return &server.Response{Code: {{if eq .Code "default"}}code{{else}}{{.Code}}{{end}}{{if .Type}}, Body: body{{end}}}
+}
+{{end}}{{end}}
+var swagger = server.MustLoad({{.Swagger}})
+
+// Handler serves si. Requests are validated against the document before
+// they reach it.
+func Handler(si ServerInterface) http.Handler {
+	return server.Handler(swagger, map[string]server.OperationFunc{
+{{range .Operations}}		"{{.Key}}": func(r *http.Request, vars map[string]string) (*server.Response, error) {
+			req := &{{.Name}}Request{}
+{{range .Params}}			if raw, ok := server.Param(r, vars, "{{.In}}", "{{.Name}}"); ok {
+{{if .Pointer}}				val := server.{{.Parse}}(raw)
+				req.{{.Field}} = &val
+{{else}}				req.{{.Field}} = server.{{.Parse}}(raw)
+{{end}}			}
+{{end}}{{with .Body}}			if err := server.DecodeBody(r, &req.Body); err != nil {
+				return nil, err
+			}
+{{end}}			return si.{{.Name}}(r.Context(), req)
+		},
+{{end}}	})
+}
+`))
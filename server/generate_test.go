@@ -0,0 +1,156 @@
+package server
+
+import (
+	"github.com/peak6/arlong/schema"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func testSwagger() *schema.Swagger {
+	swagger := schema.New()
+	swagger.Definitions["github.com.org.models.Pet"] = &schema.Schema{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]*schema.Schema{
+			"name": {Type: "string"},
+			"tags": {Type: "array", Items: &schema.Schema{Type: "string"}},
+			"age":  {Type: "integer", Nullable: true},
+		},
+	}
+	swagger.Definitions["github.com.org.models.Error"] = &schema.Schema{
+		Type:       "object",
+		Properties: map[string]*schema.Schema{"message": {Type: "string"}},
+	}
+	swagger.Paths["/pets/{id}"] = &schema.Path{
+		GET: &schema.Operation{
+			OperationId: "getPet",
+			Parameters: []*schema.Parameter{
+				{Name: "id", In: schema.PATH, Type: "integer", Format: schema.INT32, Required: true},
+				{Name: "fields", In: schema.QUERY, Type: "array", Items: &schema.Items{Type: "string"}},
+			},
+			Responses: map[string]*schema.Responses{
+				"200":     {Schema: &schema.Schema{Ref: "#/definitions/github.com.org.models.Pet"}},
+				"default": {Schema: &schema.Schema{Ref: "#/definitions/github.com.org.models.Error"}},
+			},
+		},
+		DELETE: &schema.Operation{
+			Responses: map[string]*schema.Responses{"204": {}},
+		},
+	}
+	swagger.Paths["/pets"] = &schema.Path{
+		POST: &schema.Operation{
+			OperationId: "create_pet",
+			Parameters: []*schema.Parameter{
+				{Name: "body", In: "body", Required: true, Schema: &schema.Schema{Ref: "#/definitions/github.com.org.models.Pet"}},
+			},
+			Responses: map[string]*schema.Responses{"201": {Schema: &schema.Schema{Ref: "#/definitions/github.com.org.models.Pet"}}},
+		},
+	}
+
+	return swagger
+}
+
+// typeCheck parses and type-checks a generated file against the real
+// server package.
+func typeCheck(t *testing.T, src []byte) *ast.File {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "server.gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, src)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("api", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("%s\n%s", err, src)
+	}
+
+	return file
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(testSwagger(), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := typeCheck(t, src)
+
+	decls := map[string]bool{}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			decls[d.Name.Name] = true
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					decls[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{
+		"Pet", "Error", "ServerInterface", "Handler",
+		"GetPetRequest", "GetPet200", "GetPetDefault",
+		"CreatePetRequest", "CreatePet201",
+		"DeletePetsIdRequest", "DeletePetsId204",
+	} {
+		if !decls[name] {
+			t.Errorf("missing %s in\n%s", name, src)
+		}
+	}
+
+	if code := strings.Join(strings.Fields(string(src)), " "); !strings.Contains(code, "Age *int64 `json:\"age,omitempty\"`") {
+		t.Errorf("expected a pointer for the nullable age in\n%s", src)
+	}
+}
+
+func TestGenerateCollisions(t *testing.T) {
+	swagger := testSwagger()
+	swagger.Definitions["github.com.org.models.Pet"].Properties["user_id"] = &schema.Schema{Type: "string"}
+	swagger.Definitions["github.com.org.models.Pet"].Properties["userId"] = &schema.Schema{Type: "integer"}
+	swagger.Definitions["github.com.org.models.GetPetRequest"] = &schema.Schema{Type: "string"}
+	swagger.Definitions["github.com.org.other.Error"] = &schema.Schema{Type: "string"}
+	swagger.Definitions["Handler"] = &schema.Schema{Type: "string"}
+	create := swagger.Paths["/pets"].POST
+	create.Parameters = append(create.Parameters,
+		&schema.Parameter{Name: "body", In: schema.QUERY, Type: "string"},
+		&schema.Parameter{Name: "page-size", In: schema.QUERY, Type: "integer"},
+		&schema.Parameter{Name: "page_size", In: schema.HEADER, Type: "integer"},
+	)
+	swagger.Paths["/pets"].GET = &schema.Operation{
+		OperationId: "get_pet",
+		Responses:   map[string]*schema.Responses{"200": {}},
+	}
+
+	src, err := Generate(swagger, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := typeCheck(t, src)
+	decls := map[string]bool{}
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					decls[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{
+		"GetPetRequest", "GetPet2Request", "GetPet3Request", "Handler2",
+		"GithubComOrgModelsError", "GithubComOrgOtherError",
+	} {
+		if !decls[name] {
+			t.Errorf("missing %s in\n%s", name, src)
+		}
+	}
+}
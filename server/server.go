@@ -0,0 +1,205 @@
+// Package server generates Go server stubs from a Swagger document and
+// holds the runtime the generated code is built on.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peak6/arlong/middleware"
+	"github.com/peak6/arlong/schema"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response is what an operation of a generated ServerInterface returns.
+// Generated code has a typed helper per declared status code to build it.
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   interface{}
+}
+
+// OperationFunc decodes the request of one operation and calls the
+// implementation. vars holds the values of the path parameters.
+type OperationFunc func(r *http.Request, vars map[string]string) (*Response, error)
+
+// MustLoad reads the document embedded in generated code.
+func MustLoad(doc string) *schema.Swagger {
+	swagger := schema.New()
+	if err := json.Unmarshal([]byte(doc), swagger); err != nil {
+		panic(err)
+	}
+
+	return swagger
+}
+
+// Handler routes requests to ops, keyed by "METHOD /template". Requests
+// are validated against swagger first and rejected with a 400 when they
+// do not match; errors returned by an operation become a 500.
+func Handler(swagger *schema.Swagger, ops map[string]OperationFunc) http.Handler {
+	validator := middleware.New(swagger)
+
+	return validator.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, vars, found := validator.Router().Match(r.Method, r.URL.Path)
+		op, ok := ops[routeKey(rt)]
+		if rt == nil || !ok {
+			if found {
+				middleware.WriteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path))
+				return
+			}
+			middleware.WriteError(w, http.StatusNotFound, fmt.Sprintf("No operation matches %s", r.URL.Path))
+			return
+		}
+
+		resp, err := op(r, vars)
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		WriteResponse(w, resp)
+	}))
+}
+
+func routeKey(rt *middleware.Route) string {
+	if rt == nil {
+		return ""
+	}
+
+	return rt.Method + " " + rt.Template
+}
+
+// WriteResponse writes resp, encoding its body as JSON.
+func WriteResponse(w http.ResponseWriter, resp *Response) {
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+
+	if resp.Body == nil {
+		w.WriteHeader(resp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", schema.MIME_JSON)
+	w.WriteHeader(resp.Code)
+	json.NewEncoder(w).Encode(resp.Body)
+}
+
+// Param returns the raw value of a path, query, header or form parameter.
+// Repeated query and form values are joined with commas.
+func Param(r *http.Request, vars map[string]string, in, name string) (string, bool) {
+	switch in {
+	case schema.PATH:
+		val, ok := vars[name]
+		return val, ok
+	case schema.QUERY:
+		values, ok := r.URL.Query()[name]
+		return strings.Join(values, ","), ok
+	case schema.HEADER:
+		val := r.Header.Get(name)
+		return val, val != ""
+	case schema.FORMDATA:
+		r.ParseMultipartForm(32 << 20)
+		values, ok := r.Form[name]
+		return strings.Join(values, ","), ok
+	}
+
+	return "", false
+}
+
+// DecodeBody decodes a JSON request body into v. An empty body is not an
+// error.
+func DecodeBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+// The conversions below read parameter values the validation middleware
+// has already checked, so they ignore parse errors.
+
+func String(raw string) string {
+	return raw
+}
+
+func Int32(raw string) int32 {
+	i, _ := strconv.ParseInt(raw, 10, 32)
+	return int32(i)
+}
+
+func Int64(raw string) int64 {
+	i, _ := strconv.ParseInt(raw, 10, 64)
+	return i
+}
+
+func Float32(raw string) float32 {
+	f, _ := strconv.ParseFloat(raw, 32)
+	return float32(f)
+}
+
+func Float64(raw string) float64 {
+	f, _ := strconv.ParseFloat(raw, 64)
+	return f
+}
+
+func Bool(raw string) bool {
+	b, _ := strconv.ParseBool(raw)
+	return b
+}
+
+func Strings(raw string) []string {
+	return strings.Split(raw, ",")
+}
+
+func Int32s(raw string) []int32 {
+	vals := []int32{}
+	for _, s := range Strings(raw) {
+		vals = append(vals, Int32(s))
+	}
+	return vals
+}
+
+func Int64s(raw string) []int64 {
+	vals := []int64{}
+	for _, s := range Strings(raw) {
+		vals = append(vals, Int64(s))
+	}
+	return vals
+}
+
+func Float32s(raw string) []float32 {
+	vals := []float32{}
+	for _, s := range Strings(raw) {
+		vals = append(vals, Float32(s))
+	}
+	return vals
+}
+
+func Float64s(raw string) []float64 {
+	vals := []float64{}
+	for _, s := range Strings(raw) {
+		vals = append(vals, Float64(s))
+	}
+	return vals
+}
+
+func Bools(raw string) []bool {
+	vals := []bool{}
+	for _, s := range Strings(raw) {
+		vals = append(vals, Bool(s))
+	}
+	return vals
+}
@@ -0,0 +1,748 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	"github.com/peak6/arlong/goname"
+	. "github.com/peak6/arlong/schema"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// commentBlock collects the comment lines of one annotation block.
+type commentBlock struct {
+	lines []string
+}
+
+func (a *commentBlock) add(tag string, vals ...string) {
+	line := tag
+	for _, val := range vals {
+		if val != "" {
+			line += " " + val
+		}
+	}
+	a.lines = append(a.lines, line)
+}
+
+// addText adds a multi-line text as a tag line per paragraph, followed by
+// its other lines indented, and a bare tag per blank line. It returns false
+// when the text does not read back as written: leading indentation that
+// every line of a paragraph shares is lost.
+func (a *commentBlock) addText(tag, text string) bool {
+	text = strings.Trim(text, "\n")
+	if text == "" {
+		return true
+	}
+
+	kept := true
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); {
+		if strings.TrimSpace(lines[i]) == "" {
+			a.add(tag)
+			i++
+			continue
+		}
+
+		first := lines[i]
+		a.add(tag, strings.TrimSpace(first))
+		kept = kept && !isSpace(first[0])
+
+		margin := -1
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+			line := strings.TrimRight(lines[i], " \t")
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			if margin < 0 || indent < margin {
+				margin = indent
+			}
+			if start, _ := annotationStart("// " + line); start >= 0 {
+				kept = false
+			}
+			a.lines = append(a.lines, "  "+line)
+		}
+		kept = kept && margin <= 0
+	}
+
+	return kept
+}
+
+func kv(key, val string) string {
+	return key + "=" + formatValue(val)
+}
+
+// optKV is kv for values that are left out when empty.
+func optKV(key, val string) string {
+	if val == "" {
+		return ""
+	}
+
+	return kv(key, val)
+}
+
+func intKV(key string, val int) string {
+	if val == 0 {
+		return ""
+	}
+
+	return key + "=" + strconv.Itoa(val)
+}
+
+func compactJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// exampleKV writes an example as a key=value pair. Strings that read back
+// as themselves are written as they are, other values as JSON; raw is set
+// when the parser keeps every string as written.
+func exampleKV(example interface{}, raw bool) string {
+	return kv("example", exampleText(example, raw))
+}
+
+func exampleText(example interface{}, raw bool) string {
+	val, ok := example.(string)
+	if parsed, isString := parseExample(val).(string); !ok || !raw && (!isString || parsed != val) {
+		val = compactJSON(example)
+	}
+
+	return val
+}
+
+type annotator struct {
+	swagger *Swagger
+	blocks  []*commentBlock
+}
+
+func (a *annotator) block() *commentBlock {
+	block := &commentBlock{}
+	a.blocks = append(a.blocks, block)
+	return block
+}
+
+func (a *annotator) lossy(where, what string) {
+	logrus.Warnf("%s: %s cannot be written as annotations and is dropped", where, what)
+}
+
+// enumFits reports whether enum can be written as space separated values,
+// and warns when it cannot.
+func (a *annotator) enumFits(where, what string, enum []string) bool {
+	for _, val := range enum {
+		if val == "" || strings.IndexFunc(val, unicode.IsSpace) >= 0 {
+			a.lossy(where, fmt.Sprintf("the %s with the value %q", what, val))
+			return false
+		}
+	}
+
+	return len(enum) > 0
+}
+
+func requirementVals(requirement map[string][]string) []string {
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vals := []string{}
+	for _, name := range names {
+		vals = append(vals, name+"="+strings.Join(requirement[name], ","))
+	}
+
+	return vals
+}
+
+func externalDocsVals(docs *ExternalDocs) []string {
+	vals := []string{kv("url", docs.URL)}
+	if docs.Description != "" {
+		vals = append(vals, kv("desc", docs.Description))
+	}
+
+	return vals
+}
+
+func (a *annotator) swaggerBlock() {
+	s := a.swagger
+	b := a.block()
+	b.add("@Swagger")
+	if s.Info.Title != "" {
+		b.add("@Title", s.Info.Title)
+	}
+	if !b.addText("@Description", s.Info.Description) {
+		a.lossy("info", "the indentation of a description")
+	}
+	if s.Info.Version != "" {
+		b.add("@Version", s.Info.Version)
+	}
+	if s.Info.TermsOfService != "" {
+		b.add("@Term", s.Info.TermsOfService)
+	}
+	if c := s.Info.Contact; c != nil {
+		b.add("@Contact", optKV("name", c.Name), optKV("url", c.URL), optKV("email", c.Email))
+	}
+	if l := s.Info.License; l != nil {
+		b.add("@License", optKV("name", l.Name), optKV("url", l.URL))
+	}
+	if s.Host != "" {
+		b.add("@Host", s.Host)
+	}
+	if s.BasePath != "" {
+		b.add("@BasePath", s.BasePath)
+	}
+	if len(s.Schemes) > 0 {
+		b.add("@Schemes", strings.Join(s.Schemes, " "))
+	}
+	if len(s.Consumes) > 0 {
+		b.add("@Consumes", strings.Join(s.Consumes, " "))
+	}
+	if len(s.Produces) > 0 {
+		b.add("@Produces", strings.Join(s.Produces, " "))
+	}
+	for _, requirement := range s.Security {
+		b.add("@Security", requirementVals(requirement)...)
+	}
+	if s.ExternalDocs != nil {
+		b.add("@ExternalDocs", externalDocsVals(s.ExternalDocs)...)
+	}
+	for _, tag := range s.Tags {
+		vals := []string{tag.Name}
+		if tag.Description != "" {
+			vals = append(vals, kv("desc", tag.Description))
+		}
+		if tag.ExternalDocs != nil {
+			vals = append(vals, kv("docs", tag.ExternalDocs.URL))
+			if tag.ExternalDocs.Description != "" {
+				vals = append(vals, kv("docsDesc", tag.ExternalDocs.Description))
+			}
+		}
+		b.add("@Tag", vals...)
+	}
+}
+
+func (a *annotator) securityBlocks() {
+	names := make([]string, 0, len(a.swagger.SecurityDefinitions))
+	for name := range a.swagger.SecurityDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := a.swagger.SecurityDefinitions[name]
+		b := a.block()
+		b.add("@SecurityDefinition", name)
+		b.add("@Type", def.Type)
+		if def.Name != "" {
+			b.add("@Name", def.Name)
+		}
+		if def.In != "" {
+			b.add("@In", def.In)
+		}
+		if !b.addText("@Description", def.Description) {
+			a.lossy("#/securityDefinitions/"+name, "the indentation of a description")
+		}
+		if def.Flow != "" {
+			b.add("@Flow", def.Flow)
+		}
+		if def.AuthorizationUrl != "" {
+			b.add("@AuthorizationUrl", def.AuthorizationUrl)
+		}
+		if def.TokenUrl != "" {
+			b.add("@TokenUrl", def.TokenUrl)
+		}
+		if len(def.Scopes) > 0 {
+			scopes := make([]string, 0, len(def.Scopes))
+			for scope := range def.Scopes {
+				scopes = append(scopes, scope)
+			}
+			sort.Strings(scopes)
+			vals := []string{}
+			for _, scope := range scopes {
+				vals = append(vals, kv(scope, def.Scopes[scope]))
+			}
+			b.add("@Scopes", vals...)
+		}
+	}
+}
+
+// schemaVals writes the parts of a schema that @Param, @Response and
+// @Items can hold, with every key prefixed.
+func (a *annotator) schemaVals(where, prefix string, s *Schema) []string {
+	if s == nil {
+		return nil
+	}
+
+	vals := []string{}
+	if s.Ref != "" {
+		vals = append(vals, kv(prefix+"$ref", removeDefinitionRef(s.Ref)))
+	}
+	if s.Type != "" {
+		vals = append(vals, kv(prefix+"type", s.Type))
+	}
+	if s.Format != "" {
+		vals = append(vals, kv(prefix+"format", s.Format))
+	}
+	if s.Items != nil {
+		vals = append(vals, a.schemaVals(where, prefix+"items.", s.Items)...)
+	}
+	if len(s.Properties) > 0 || s.AdditionalProperties != nil || len(s.AllOf) > 0 {
+		a.lossy(where, "an inline object schema")
+	}
+
+	return vals
+}
+
+func (a *annotator) paramVals(where string, param *Parameter) []string {
+	if param.Ref != "" {
+		return []string{kv("$ref", strings.TrimPrefix(param.Ref, "#/parameters/"))}
+	}
+
+	vals := []string{kv("name", param.Name), kv("in", param.In)}
+	if param.Required {
+		vals = append(vals, "required")
+	}
+	if param.Description != "" {
+		vals = append(vals, kv("desc", param.Description))
+	}
+	if param.Type != "" {
+		vals = append(vals, kv("type", param.Type))
+	}
+	if param.Format != "" {
+		vals = append(vals, kv("format", param.Format))
+	}
+	if param.AllowEmptyValue {
+		vals = append(vals, "allowEmptyValue")
+	}
+	if param.CollectionFormat != "" {
+		vals = append(vals, kv("collectionFormat", param.CollectionFormat))
+	}
+	if param.Default != nil {
+		vals = append(vals, kv("default", fmt.Sprint(param.Default)))
+	}
+	vals = append(vals,
+		intKV("maximum", param.Maximum), intKV("minimum", param.Minimum),
+		intKV("maxLength", param.MaxLength), intKV("minLength", param.MinLength),
+		intKV("maxItems", param.MaxItems), intKV("minItems", param.MinItems),
+	)
+	if a.enumFits(where, "enum", param.Enum) {
+		vals = append(vals, kv("enum", strings.Join(param.Enum, " ")))
+	}
+	if item := param.Items; item != nil {
+		if item.Type != "" {
+			vals = append(vals, kv("items.type", item.Type))
+		}
+		if item.Format != "" {
+			vals = append(vals, kv("items.format", item.Format))
+		}
+		if item.Default != nil {
+			vals = append(vals, kv("items.default", fmt.Sprint(item.Default)))
+		}
+		if a.enumFits(where, "items.enum", item.Enum) {
+			vals = append(vals, kv("items.enum", strings.Join(item.Enum, " ")))
+		}
+		vals = append(vals,
+			intKV("items.maximum", item.Maximum), intKV("items.minimum", item.Minimum),
+			intKV("items.maxLength", item.MaxLength), intKV("items.minLength", item.MinLength),
+			intKV("items.maxItems", item.MaxItems), intKV("items.minItems", item.MinItems),
+		)
+	}
+	vals = append(vals, a.schemaVals(where, "schema.", param.Schema)...)
+
+	if param.Schema != nil && param.Schema.Example != nil {
+		vals = append(vals, exampleKV(param.Schema.Example, false))
+	} else if param.Example != nil {
+		vals = append(vals, exampleKV(param.Example, param.Type == "" || param.Type == "string"))
+	}
+
+	return vals
+}
+
+func (a *annotator) responseVals(where string, resp *Responses) []string {
+	if resp.Ref != "" {
+		return []string{kv("$ref", strings.TrimPrefix(resp.Ref, "#/responses/"))}
+	}
+
+	vals := []string{}
+	if resp.Description != "" {
+		vals = append(vals, kv("desc", resp.Description))
+	}
+	if len(resp.Headers) > 0 {
+		a.lossy(where, "response headers")
+	}
+
+	return append(vals, a.schemaVals(where, "schema.", resp.Schema)...)
+}
+
+func (a *annotator) globalBlocks() {
+	names := make([]string, 0, len(a.swagger.Parameters))
+	for name := range a.swagger.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		b := a.block()
+		for _, name := range names {
+			b.add("@GlobalParam", append([]string{name}, a.paramVals("#/parameters/"+name, a.swagger.Parameters[name])...)...)
+		}
+	}
+
+	names = names[:0]
+	for name := range a.swagger.Responses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		b := a.block()
+		for _, name := range names {
+			resp := a.swagger.Responses[name]
+			b.add("@GlobalResponse", append([]string{name}, a.responseVals("#/responses/"+name, resp)...)...)
+			if len(resp.Examples) > 0 {
+				a.lossy("#/responses/"+name, "examples")
+			}
+		}
+	}
+}
+
+func (a *annotator) propertyVals(where string, prop *Schema) []string {
+	vals := []string{}
+	if prop.Ref != "" {
+		vals = append(vals, kv("$ref", removeDefinitionRef(prop.Ref)))
+	}
+	if prop.Type != "" {
+		vals = append(vals, kv("type", prop.Type))
+	}
+	if prop.Format != "" {
+		vals = append(vals, kv("format", prop.Format))
+	}
+	if prop.Description != "" {
+		vals = append(vals, kv("desc", prop.Description))
+	}
+	if a.enumFits(where, "enum", prop.Enum) {
+		vals = append(vals, kv("enum", strings.Join(prop.Enum, " ")))
+	}
+	if prop.Example != nil {
+		vals = append(vals, exampleKV(prop.Example, prop.Type == "string"))
+	}
+	if prop.Nullable {
+		vals = append(vals, "nullable")
+	}
+	vals = append(vals,
+		intKV("maximum", prop.Maximum), intKV("minimum", prop.Minimum),
+		intKV("maxLength", prop.MaxLength), intKV("minLength", prop.MinLength),
+		intKV("maxItems", prop.MaxItems), intKV("minItems", prop.MinItems),
+	)
+	if prop.Items != nil {
+		vals = append(vals, a.schemaVals(where, "items.", prop.Items)...)
+	}
+	if len(prop.Properties) > 0 || prop.AdditionalProperties != nil || len(prop.AllOf) > 0 {
+		a.lossy(where, "an inline object schema")
+	}
+
+	return vals
+}
+
+func (a *annotator) definitionBlocks() {
+	names := make([]string, 0, len(a.swagger.Definitions))
+	for name := range a.swagger.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := a.swagger.Definitions[name]
+		where := "#/definitions/" + name
+		b := a.block()
+		b.add("@Definition", name)
+		if def.Type != "" {
+			b.add("@Type", def.Type)
+		}
+		if def.Format != "" {
+			b.add("@Format", def.Format)
+		}
+		if !b.addText("@Description", def.Description) {
+			a.lossy(where, "the indentation of a description")
+		}
+		if len(def.Required) > 0 {
+			b.add("@Required", strings.Join(def.Required, " "))
+		}
+		if a.enumFits(where, "@Enum", def.Enum) {
+			b.add("@Enum", strings.Join(def.Enum, " "))
+		}
+		if def.Items != nil {
+			b.add("@Items", a.schemaVals(where, "", def.Items)...)
+		}
+		if def.Example != nil {
+			b.add("@Example", exampleText(def.Example, def.Type == "string"))
+		}
+		if def.ExternalDocs != nil {
+			b.add("@ExternalDocs", externalDocsVals(def.ExternalDocs)...)
+		}
+		if len(def.AllOf) > 0 || def.AdditionalProperties != nil {
+			a.lossy(where, "allOf and additionalProperties")
+		}
+
+		props := make([]string, 0, len(def.Properties))
+		for prop := range def.Properties {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+		for _, prop := range props {
+			b.add("@Property", append([]string{prop}, a.propertyVals(where+"/"+prop, def.Properties[prop])...)...)
+		}
+	}
+}
+
+func (a *annotator) pathBlocks() {
+	routes := make([]string, 0, len(a.swagger.Paths))
+	for route := range a.swagger.Paths {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	for _, route := range routes {
+		path := a.swagger.Paths[route]
+		if len(path.Parameters) > 0 {
+			a.lossy(route, "path level parameters")
+		}
+
+		for _, m := range []struct {
+			name string
+			op   *Operation
+		}{
+			{"GET", path.GET}, {"PUT", path.PUT}, {"POST", path.POST}, {"DELETE", path.DELETE},
+			{"OPTIONS", path.OPTIONS}, {"HEAD", path.HEAD}, {"PATCH", path.PATCH},
+		} {
+			if m.op != nil {
+				a.operationBlock(route, m.name, m.op)
+			}
+		}
+	}
+}
+
+func (a *annotator) operationBlock(route, method string, op *Operation) {
+	where := method + " " + route
+	b := a.block()
+	b.add("@Path", route)
+	b.add("@Method", method)
+	if op.Summary != "" {
+		b.add("@Summary", op.Summary)
+	}
+	if !b.addText("@Description", op.Description) {
+		a.lossy(where, "the indentation of a description")
+	}
+	if op.OperationId != "" {
+		b.add("@OperationId", op.OperationId)
+	}
+	if len(op.Tags) > 0 {
+		b.add("@Tags", strings.Join(op.Tags, " "))
+	}
+	if len(op.Consumes) > 0 {
+		b.add("@Consumes", strings.Join(op.Consumes, " "))
+	}
+	if len(op.Produces) > 0 {
+		b.add("@Produces", strings.Join(op.Produces, " "))
+	}
+	if len(op.Schemes) > 0 {
+		b.add("@Schemes", strings.Join(op.Schemes, " "))
+	}
+	if op.Deprecated {
+		b.add("@Deprecated")
+	}
+	if op.ExternalDocs != nil {
+		b.add("@ExternalDocs", externalDocsVals(op.ExternalDocs)...)
+	}
+	if op.Security != nil && len(op.Security) == 0 {
+		a.lossy(where, "an empty security requirement")
+	}
+	for _, requirement := range op.Security {
+		if len(requirement) == 1 {
+			for name, scopes := range requirement {
+				if len(scopes) == 0 {
+					b.add("@Security", name)
+				} else {
+					b.add("@Security", requirementVals(requirement)...)
+				}
+			}
+			continue
+		}
+		b.add("@Security", requirementVals(requirement)...)
+	}
+	for _, param := range op.Parameters {
+		b.add("@Param", a.paramVals(where+" "+param.Name, param)...)
+	}
+
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		resp := op.Responses[code]
+		b.add("@Response", append([]string{code}, a.responseVals(where+" "+code, resp)...)...)
+
+		mimes := make([]string, 0, len(resp.Examples))
+		for mime := range resp.Examples {
+			mimes = append(mimes, mime)
+		}
+		sort.Strings(mimes)
+		for _, mime := range mimes {
+			b.add("@Example", code, mime, compactJSON(resp.Examples[mime]))
+		}
+	}
+}
+
+// skeletons writes a struct for every object definition whose properties
+// all have a Go type. usesTime reports whether time.Time was needed.
+func (a *annotator) skeletons(buf *bytes.Buffer) (usesTime bool) {
+	defs := a.swagger.Definitions
+	keys := make([]string, 0, len(defs))
+	for name := range defs {
+		keys = append(keys, name)
+	}
+	goNames := goname.Definitions(keys, goname.NewScope())
+
+	candidates := make(map[string]bool)
+	for name, def := range defs {
+		if (def.Type == "object" || def.Type == "") && len(def.Properties) > 0 && len(def.AllOf) == 0 {
+			candidates[name] = true
+		}
+	}
+
+	var goType func(s *Schema) (string, bool)
+	goType = func(s *Schema) (string, bool) {
+		if s == nil {
+			return "", false
+		}
+		if s.Ref != "" {
+			name := removeDefinitionRef(s.Ref)
+			return goNames[name], candidates[name]
+		}
+
+		switch s.Type {
+		case "string":
+			if s.Format == "date-time" {
+				return "time.Time", true
+			}
+			return "string", true
+		case "integer":
+			if s.Format == INT32 {
+				return "int32", true
+			}
+			return "int64", true
+		case "number":
+			if s.Format == FLOAT {
+				return "float32", true
+			}
+			return "float64", true
+		case "boolean":
+			return "bool", true
+		case "array":
+			item, ok := goType(s.Items)
+			return "[]" + item, ok
+		case "object":
+			if s.AdditionalProperties != nil && len(s.Properties) == 0 {
+				value, ok := goType(s.AdditionalProperties)
+				return "map[string]" + value, ok
+			}
+		}
+
+		return "", false
+	}
+
+	// drop definitions until every remaining one only refers to others
+	for changed := true; changed; {
+		changed = false
+		for name := range candidates {
+			for _, prop := range defs[name].Properties {
+				if _, ok := goType(prop); !ok {
+					delete(candidates, name)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := defs[name]
+		required := make(map[string]bool)
+		for _, r := range def.Required {
+			required[r] = true
+		}
+
+		props := make([]string, 0, len(def.Properties))
+		for prop := range def.Properties {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+
+		fmt.Fprintf(buf, "\n// %s is a skeleton of the %s definition. Replace the @Definition\n", goNames[name], name)
+		fmt.Fprintf(buf, "// block with @DefinitionModel on this type to generate it from Go.\n")
+		fmt.Fprintf(buf, "type %s struct {\n", goNames[name])
+		fields := goname.NewScope()
+		for _, prop := range props {
+			typ, _ := goType(def.Properties[prop])
+			if def.Properties[prop].Nullable {
+				typ = "*" + typ
+			}
+			usesTime = usesTime || strings.Contains(typ, "time.Time")
+			tag := prop
+			if !required[prop] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(buf, "\t%s %s `json:%q`\n", fields.Unique(prop), typ, tag)
+		}
+		fmt.Fprintf(buf, "}\n")
+	}
+
+	return usesTime
+}
+
+// Annotate writes a Go file in package pkg whose annotations describe
+// swagger, with a struct skeleton for every definition that maps to Go
+// types. Parsing the file gives back an equivalent document, except for
+// what annotations cannot express (inline object schemas, allOf,
+// additionalProperties, response headers, path level parameters), which is
+// dropped with a warning.
+func Annotate(swagger *Swagger, pkg string) ([]byte, error) {
+	a := &annotator{swagger: swagger}
+	a.swaggerBlock()
+	a.securityBlocks()
+	a.globalBlocks()
+	a.definitionBlocks()
+	a.pathBlocks()
+
+	types := &bytes.Buffer{}
+	usesTime := a.skeletons(types)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	if usesTime {
+		fmt.Fprintf(buf, "import \"time\"\n\n")
+	}
+	fmt.Fprintf(buf, "// Annotations imported from a Swagger document with arlong import.\n")
+	for _, block := range a.blocks {
+		fmt.Fprintf(buf, "\n")
+		for _, line := range block.lines {
+			fmt.Fprintf(buf, "// %s\n", line)
+		}
+		fmt.Fprintf(buf, "//\n")
+	}
+	buf.Write(types.Bytes())
+
+	return format.Source(buf.Bytes())
+}
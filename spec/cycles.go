@@ -0,0 +1,134 @@
+package spec
+
+import (
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"github.com/peak6/utils/parsetype"
+	"strings"
+)
+
+// walkFrame is a type the model walker is currently inside of.
+type walkFrame struct {
+	typ     *parsetype.Type
+	key     string
+	def     *Schema
+	entered bool
+	hoist   string
+}
+
+func (f *walkFrame) name() string {
+	switch {
+	case f.key != "":
+		return f.key
+	case f.typ.Name != "":
+		return shortTypeName(f.typ.Name)
+	}
+
+	return "struct{...}"
+}
+
+// parseModel walks pType into def, the schema stored under the definition
+// key, so that recursive references back to it become $refs.
+func (p *Parser) parseModel(key string, def *Schema, pType *parsetype.Type) {
+	p.walking = append(p.walking, &walkFrame{typ: pType, key: key, def: def})
+	p.parseDefinitionModel(def, pType)
+	p.walking = p.walking[:len(p.walking)-1]
+}
+
+func (p *Parser) findFrame(pType *parsetype.Type) (int, *walkFrame) {
+	for i, frame := range p.walking {
+		if frame.typ == pType {
+			return i, frame
+		}
+	}
+
+	return -1, nil
+}
+
+// enterStruct is called before the walker descends into a struct. It
+// returns false when the struct is already being walked, in which case the
+// cycle is cut by pointing def at the struct's definition. The returned
+// frame, if any, must be passed to leaveStruct.
+func (p *Parser) enterStruct(def *Schema, pType *parsetype.Type) (*walkFrame, bool) {
+	index, frame := p.findFrame(pType)
+	if frame == nil {
+		frame = &walkFrame{typ: pType, def: def, entered: true}
+		p.walking = append(p.walking, frame)
+		return frame, true
+	}
+
+	if !frame.entered {
+		frame.entered = true
+		return nil, true
+	}
+
+	key := frame.key
+	if key == "" {
+		key = frame.hoist
+	}
+	if key == "" && pType.Name != "" {
+		// an inline struct refers back to itself, move it to a definition
+		key = p.registerModel(pType.Name)
+		frame.hoist = key
+	}
+
+	p.reportCycle(index)
+	if key != "" {
+		def.Ref = "#/definitions/" + key
+	} else {
+		def.Type = "object"
+	}
+
+	return nil, false
+}
+
+func (p *Parser) leaveStruct(frame *walkFrame) {
+	p.walking = p.walking[:len(p.walking)-1]
+	if frame.hoist == "" {
+		return
+	}
+
+	hoisted := *frame.def
+	p.swagger.Definitions[frame.hoist] = &hoisted
+	*frame.def = Schema{Ref: "#/definitions/" + frame.hoist, Nullable: hoisted.Nullable}
+}
+
+// cutCycle reports whether a $ref to key points back at a type that is
+// still being walked. An inline struct met this way is moved to key.
+func (p *Parser) cutCycle(key string, pType *parsetype.Type) bool {
+	index, frame := p.findFrame(pType)
+	if frame == nil {
+		return false
+	}
+
+	if frame.key == "" && frame.hoist == "" {
+		frame.hoist = key
+	}
+	p.reportCycle(index)
+
+	return true
+}
+
+func (p *Parser) reportCycle(index int) {
+	names := []string{}
+	for _, frame := range p.walking[index:] {
+		names = append(names, frame.name())
+	}
+	names = append(names, p.walking[index].name())
+
+	cycle := strings.Join(names, " -> ")
+	for _, known := range p.cycles {
+		if known == cycle {
+			return
+		}
+	}
+
+	logrus.Warnf("Cut recursive type %s with a $ref", cycle)
+	p.cycles = append(p.cycles, cycle)
+}
+
+// Cycles returns the recursive type paths the walker cut with a $ref, in
+// the form A -> B -> A.
+func (p *Parser) Cycles() []string {
+	return p.cycles
+}
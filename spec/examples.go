@@ -0,0 +1,160 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// parseExample reads an example value as JSON, falling back to the plain
+// string when it is not valid JSON.
+func parseExample(s string) interface{} {
+	var val interface{}
+	if err := json.Unmarshal([]byte(s), &val); err != nil {
+		return s
+	}
+
+	return val
+}
+
+// typedExample converts an example for a parameter of the given type.
+// Values of string parameters are kept as written.
+func typedExample(typ, s string) interface{} {
+	switch typ {
+	case "", "string":
+		return s
+	}
+
+	return parseExample(s)
+}
+
+// rawExample is the example of a definition or a property as written. It
+// is converted by typeExamples once the type of the schema is known.
+type rawExample string
+
+// typeExamples converts the examples of definitions and their properties.
+// Examples of strings are kept as written, so "123" or true stay strings.
+func (p *Parser) typeExamples() {
+	for _, def := range p.swagger.Definitions {
+		walkSchema(def, func(s *Schema) {
+			raw, ok := s.Example.(rawExample)
+			if !ok {
+				return
+			}
+
+			if s.Type == "string" {
+				s.Example = string(raw)
+			} else {
+				s.Example = parseExample(string(raw))
+			}
+		})
+	}
+}
+
+// parseResponseExample reads "@Example <code> <mime> <json-or-file>" inside a
+// @Path block. A value that is not JSON is read from a file relative to the
+// source file.
+func (p *Parser) parseResponseExample(method *Operation, ann *annotation) {
+	if len(ann.args()) < 3 {
+		panic(ann.errorAt(0, "expected a code, a mime type and the example"))
+	}
+
+	code, mime, value := ann.word(0), getMime(ann.word(1)), ann.rest(2)
+	if method.Responses == nil {
+		method.Responses = make(map[string]*Responses)
+	}
+	if method.Responses[code] == nil {
+		method.Responses[code] = &Responses{}
+	}
+
+	resp := method.Responses[code]
+	if resp.Examples == nil {
+		resp.Examples = make(map[string]interface{})
+	}
+	resp.Examples[mime] = p.exampleValue(ann, value)
+}
+
+func (p *Parser) exampleValue(ann *annotation, value string) interface{} {
+	var val interface{}
+	if err := json.Unmarshal([]byte(value), &val); err == nil {
+		return val
+	}
+
+	filename := value
+	if p.fset != nil && !filepath.IsAbs(filename) {
+		if source := p.fset.Position(ann.pos).Filename; source != "" {
+			filename = filepath.Join(filepath.Dir(source), filename)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(ann.errorAt(ann.args()[2].offset, fmt.Sprintf("cannot read the example: %s", err)))
+	}
+
+	return parseExample(string(b))
+}
+
+// validateExamples checks every explicit example against the schema it
+// documents and returns the mismatches.
+func (p *Parser) validateExamples() []string {
+	problems := []string{}
+	check := func(s *Schema, value interface{}, path string) {
+		for _, violation := range s.Validate(value, p.swagger) {
+			problems = append(problems, fmt.Sprintf("%s%s: %s", path, violation.Path, violation.Message))
+		}
+	}
+
+	for name, def := range p.swagger.Definitions {
+		walkSchema(def, func(s *Schema) {
+			if s.Example != nil {
+				check(s, s.Example, "#/definitions/"+name)
+			}
+		})
+	}
+
+	checkParam := func(param *Parameter, path string) {
+		if param.Example != nil {
+			check(&Schema{Type: param.Type, Enum: param.Enum}, param.Example, path)
+		}
+		if param.Schema != nil && param.Schema.Example != nil {
+			check(param.Schema, param.Schema.Example, path)
+		}
+	}
+	checkResp := func(resp *Responses, path string) {
+		for mime, example := range resp.Examples {
+			if strings.Contains(mime, "json") {
+				check(resp.Schema, example, path+"/examples/"+mime)
+			}
+		}
+	}
+
+	for name, param := range p.swagger.Parameters {
+		checkParam(param, "#/parameters/"+name)
+	}
+	for name, resp := range p.swagger.Responses {
+		checkResp(resp, "#/responses/"+name)
+	}
+	for route, path := range p.swagger.Paths {
+		for _, op := range path.Operations() {
+			for _, param := range op.Parameters {
+				checkParam(param, route+" "+param.Name)
+			}
+			for code, resp := range op.Responses {
+				checkResp(resp, route+" "+code)
+			}
+		}
+	}
+
+	sort.Strings(problems)
+	for _, problem := range problems {
+		logrus.Errorf("Invalid example %s", problem)
+	}
+
+	return problems
+}
@@ -0,0 +1,364 @@
+package spec
+
+import (
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"github.com/peak6/utils/parsetype"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// structSource is the declaration of a named struct type.
+type structSource struct {
+	raw    string
+	file   *ast.File
+	fields map[string]*ast.Field
+}
+
+// goFields returns the struct fields of the named type keyed by field name,
+// embedded fields by their type name. The type walker drops pointers,
+// embedding and inline struct types, so they are read from the package
+// source.
+func (p *Parser) goFields(raw string) *structSource {
+	if model, ok := p.models[fixPath(raw)]; ok && strings.Contains(model.raw, "/") {
+		raw = model.raw
+	}
+
+	src := &structSource{raw: raw, fields: map[string]*ast.Field{}}
+	_, typeName := splitTypeName(raw)
+	for _, f := range p.packageSource(typePackage(raw)) {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gen.Specs {
+				ts := s.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if ts.Name.Name != typeName || !ok {
+					continue
+				}
+				src.file = f
+				for _, field := range st.Fields.List {
+					if len(field.Names) == 0 {
+						src.fields[embeddedName(field.Type)] = field
+					}
+					for _, name := range field.Names {
+						src.fields[name.Name] = field
+					}
+				}
+			}
+		}
+	}
+
+	return src
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	}
+
+	return ""
+}
+
+// isEmbedded reports whether the struct property key is an embedded field.
+// Without source it falls back to comparing the key with the type name.
+func isEmbedded(fields map[string]*ast.Field, key string, val *parsetype.Type) bool {
+	if field, ok := fields[key]; ok {
+		return len(field.Names) == 0
+	}
+
+	if val.Type == "ref" && val.RefType != nil {
+		_, typeName := splitTypeName(val.RefType.Name)
+		return typeName == key
+	}
+
+	return false
+}
+
+func isPointer(fields map[string]*ast.Field, key string) bool {
+	if field, ok := fields[key]; ok {
+		_, ok := field.Type.(*ast.StarExpr)
+		return ok
+	}
+
+	return false
+}
+
+// isNullable reports whether a field can be encoded as null: pointers,
+// maps and slices.
+func isNullable(fields map[string]*ast.Field, key string, val *parsetype.Type) bool {
+	if field, ok := fields[key]; ok {
+		return nullableExpr(field.Type)
+	}
+
+	return val.Type == "map" || val.Type == "array"
+}
+
+func nullableExpr(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr, *ast.MapType:
+		return true
+	case *ast.ArrayType:
+		return t.Len == nil
+	}
+
+	return false
+}
+
+func hasOption(options []string, option string) bool {
+	for _, opt := range options {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyStringOption rewrites the schema of a field tagged json:",string",
+// which encoding/json sends quoted.
+func applyStringOption(def *Schema) {
+	switch def.Type {
+	case "integer", "number", "boolean":
+		def.Type = "string"
+	}
+}
+
+func appendRequired(def *Schema, name string) {
+	for _, required := range def.Required {
+		if required == name {
+			return
+		}
+	}
+
+	def.Required = append(def.Required, name)
+}
+
+// jsonField is the property a struct field is encoded as, with what
+// encoding/json needs to choose between fields of the same name.
+type jsonField struct {
+	name     string
+	depth    int
+	tagged   bool
+	required bool
+	schema   *Schema
+}
+
+// fieldSpec is a struct field as read from the model walker or the source.
+type fieldSpec struct {
+	key      string
+	json     string
+	arlong   string
+	doc      []*ast.Comment
+	embedded bool
+	pointer  bool
+	nullable bool
+}
+
+// readField reads a struct field into the property it is encoded as,
+// applying its json and arlong tags and annotations. build fills in the
+// schema of the field's type. It returns promote for an untagged embedded
+// field, whose fields are promoted instead, and neither for a field that
+// is not encoded.
+func (p *Parser) readField(f fieldSpec, build func(prop *Schema)) (field *jsonField, promote bool) {
+	name, options := "", []string{}
+	if f.json != "" {
+		data := strings.Split(f.json, ",")
+		if data[0] == "-" && len(data) == 1 {
+			return nil, false
+		}
+		name, options = data[0], data[1:]
+	}
+	if name == "" && f.doc != nil {
+		name = p.parsePropertiesName(f.doc)
+	}
+
+	tagged := name != ""
+	if !tagged && f.embedded {
+		return nil, true
+	}
+	if !tagged {
+		name = f.key
+	}
+
+	// collects the required marks of the field
+	owner := &Schema{}
+	prop := &Schema{}
+	applyArlongTag(owner, name, prop, f.arlong)
+	if f.doc != nil {
+		p.parsePropertiesOptions(name, owner, prop, f.doc)
+	}
+
+	build(prop)
+
+	if hasOption(options, "string") {
+		applyStringOption(prop)
+	}
+
+	prop.Nullable = f.nullable
+	if f.doc != nil {
+		if nullable, ok := p.parsePropertiesNullable(f.doc); ok {
+			prop.Nullable = nullable
+		}
+	}
+
+	required := len(owner.Required) > 0 || p.InferRequired && !hasOption(options, "omitempty") && !f.pointer
+
+	return &jsonField{name: name, tagged: tagged, required: required, schema: prop}, false
+}
+
+// applyArlongTag reads an arlong:"required,minimum=1" tag of the property
+// name of def.
+func applyArlongTag(def *Schema, name string, prop *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		data := strings.Split(opt, "=")
+		switch {
+		case data[0] == "required":
+			appendRequired(def, name)
+		case data[0] == "type":
+			prop.Type, prop.Format, _ = getTypeFormat(data[1])
+		case data[0] == "description" || data[0] == "desc":
+			prop.Description = joinLines(prop.Description, data[1])
+		case data[0] == "enum":
+			valsArray := getValueStrings(data[1])
+			for i := 0; i < len(valsArray); i++ {
+				valsArray[i] = getMime(valsArray[i])
+			}
+			prop.Enum = valsArray
+		case len(data) == 2 && numberKeys[data[0]] && !isInt(data[1]):
+			logrus.Errorf("Ignored arlong tag %s of %s: expected an integer", opt, name)
+		case len(data) == 2:
+			parseSchemaConstraint(prop, data[0], data[1])
+		}
+	}
+}
+
+// modelFields reads the fields of a struct type from the model walker,
+// and the fields promoted from its untagged embedded structs one level
+// deeper. chain holds the embedded structs being read.
+func (p *Parser) modelFields(pType *parsetype.Type, depth int, chain map[string]bool) []*jsonField {
+	src := p.goFields(pType.Name)
+	fields := []*jsonField{}
+	for key, val := range pType.Properties {
+		spec := fieldSpec{
+			key:      key,
+			json:     val.Tags.Get(`json`),
+			arlong:   val.Tags.Get(`arlong`),
+			embedded: isEmbedded(src.fields, key, val),
+			pointer:  isPointer(src.fields, key),
+			nullable: isNullable(src.fields, key, val),
+		}
+		if val.Doc != nil {
+			spec.doc = val.Doc.List
+		}
+
+		field, promote := p.readField(spec, func(prop *Schema) {
+			if raw, ok := p.instance.fieldType(src, src.fields[key]); ok {
+				// the walker cannot resolve type parameters
+				p.applyInline(prop, p.typeSchema(raw))
+			} else {
+				p.parseDefinitionModel(prop, val)
+			}
+
+			if field, ok := src.fields[key]; ok && hasInlineStruct(field.Type) {
+				p.applyInline(prop, p.exprSchema(field.Type, src.file, typePackage(src.raw), src.raw+key))
+			}
+		})
+
+		switch {
+		case promote:
+			fields = append(fields, p.embeddedFields(key, val, depth, chain)...)
+		case field != nil:
+			field.depth = depth
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// embeddedFields returns the fields an untagged embedded field contributes
+// to a struct read at depth: the fields of a struct one level deeper, or
+// a non-struct type under its type name.
+func (p *Parser) embeddedFields(key string, val *parsetype.Type, depth int, chain map[string]bool) []*jsonField {
+	target := val
+	if val.Type == "ref" && val.RefType != nil {
+		target = val.RefType
+	}
+
+	if target.Type != "struct" {
+		prop := &Schema{}
+		p.parseDefinitionModel(prop, val)
+		return []*jsonField{{name: key, depth: depth, schema: prop}}
+	}
+
+	if chain[target.Name] {
+		return nil
+	}
+	chain[target.Name] = true
+	defer delete(chain, target.Name)
+
+	return p.modelFields(target, depth+1, chain)
+}
+
+// promote sets the properties of def from the fields of a struct the way
+// encoding/json chooses between fields of the same name: the shallowest
+// one wins, then the only tagged one at that depth, and otherwise the name
+// is dropped.
+func promote(def *Schema, fields []*jsonField) {
+	byName := map[string][]*jsonField{}
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	for name, group := range byName {
+		shallowest := []*jsonField{}
+		for _, f := range group {
+			switch {
+			case len(shallowest) == 0 || f.depth < shallowest[0].depth:
+				shallowest = []*jsonField{f}
+			case f.depth == shallowest[0].depth:
+				shallowest = append(shallowest, f)
+			}
+		}
+
+		tagged := []*jsonField{}
+		for _, f := range shallowest {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+
+		var dominant *jsonField
+		switch {
+		case len(shallowest) == 1:
+			dominant = shallowest[0]
+		case len(tagged) == 1:
+			dominant = tagged[0]
+		default:
+			continue
+		}
+
+		def.Properties[name] = dominant.schema
+		if dominant.required {
+			appendRequired(def, name)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package spec
+
+import (
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"github.com/peak6/utils/parsetype"
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// splitGeneric splits an instantiation such as pkg.Page[pkg.User] into its
+// generic type and type arguments. Nested instantiations are kept intact.
+func splitGeneric(raw string) (string, []string, bool) {
+	raw = strings.Replace(raw, " ", "", -1)
+	start := strings.Index(raw, "[")
+	if start <= 0 || !strings.HasSuffix(raw, "]") || raw[:start] == "map" {
+		return raw, nil, false
+	}
+
+	args := []string{}
+	depth := 0
+	last := start + 1
+	for i := start + 1; i < len(raw)-1; i++ {
+		switch raw[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, raw[last:i])
+				last = i + 1
+			}
+		}
+	}
+	args = append(args, raw[last:len(raw)-1])
+
+	return raw[:start], args, true
+}
+
+// argName turns a type argument into the part of an instance name that
+// represents it, e.g. pkg.User -> User, []pkg.User -> UserList.
+func argName(arg string) string {
+	switch {
+	case strings.HasPrefix(arg, "[]"):
+		return argName(arg[2:]) + "List"
+	case strings.HasPrefix(arg, "*"):
+		return argName(arg[1:])
+	case strings.HasPrefix(arg, "map["):
+		if end := strings.Index(arg, "]"); end > 0 {
+			return argName(arg[end+1:]) + "Map"
+		}
+	}
+
+	if base, args, ok := splitGeneric(arg); ok {
+		name := argName(base)
+		for _, a := range args {
+			name += argName(a)
+		}
+		return name
+	}
+
+	_, typeName := splitTypeName(arg)
+	runes := []rune(typeName)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+
+	return string(runes)
+}
+
+// instanceRaw returns the type name used for an instantiation, placed in
+// the package of its generic type: pkg.Page[pkg.User] -> pkg.PageUser.
+func instanceRaw(raw string) string {
+	base, args, ok := splitGeneric(raw)
+	if !ok {
+		return raw
+	}
+
+	name := base
+	for _, arg := range args {
+		name += argName(arg)
+	}
+
+	return name
+}
+
+// definitionKey returns the definition key a $ref to raw resolves to.
+func definitionKey(raw string) string {
+	return fixPath(instanceRaw(raw))
+}
+
+// namedTypes returns every named type mentioned in raw, including type
+// arguments.
+func namedTypes(raw string) []string {
+	raw = strings.TrimLeft(raw, "[]*")
+	if strings.HasPrefix(raw, "map[") {
+		if end := strings.Index(raw, "]"); end > 0 {
+			return append(namedTypes(raw[4:end]), namedTypes(raw[end+1:])...)
+		}
+	}
+
+	base, args, ok := splitGeneric(raw)
+	if !ok {
+		if _, _, primitive := getTypeFormat(raw); primitive || !strings.Contains(raw, ".") {
+			return nil
+		}
+		return []string{raw}
+	}
+
+	names := []string{base}
+	for _, arg := range args {
+		names = append(names, namedTypes(arg)...)
+	}
+
+	return names
+}
+
+func typePackage(raw string) string {
+	index := strings.LastIndex(raw, ".")
+	if index > 0 {
+		return raw[:index]
+	}
+
+	return raw
+}
+
+// lookupType returns the parsed type for raw, parsing its package on demand.
+func (p *Parser) lookupType(raw string) (*parsetype.Type, bool) {
+	if pType, ok := p.types.Types[raw]; ok {
+		return pType, true
+	}
+
+	p.types.ParseFromGoPath(typePackage(raw))
+	p.types.MergeComposite()
+	pType, ok := p.types.Types[raw]
+
+	return pType, ok
+}
+
+// typeParams returns the type parameter names of the generic type raw. The
+// type walker does not know about type parameters, so they are read from the
+// package source directly.
+func (p *Parser) typeParams(raw string) []string {
+	if params, ok := p.generics[raw]; ok {
+		return params
+	}
+
+	params := []string{}
+	_, typeName := splitTypeName(raw)
+	for _, f := range p.packageSource(typePackage(raw)) {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gen.Specs {
+				ts := s.(*ast.TypeSpec)
+				if ts.Name.Name != typeName || ts.TypeParams == nil {
+					continue
+				}
+				for _, field := range ts.TypeParams.List {
+					for _, name := range field.Names {
+						params = append(params, name.Name)
+					}
+				}
+			}
+		}
+	}
+
+	p.generics[raw] = params
+
+	return params
+}
+
+// sameInstance reports whether two instantiations have the same type
+// arguments, allowing for names written without their full import path.
+func sameInstance(a, b string) bool {
+	a = strings.NewReplacer(" ", "", "*", "").Replace(a)
+	b = strings.NewReplacer(" ", "", "*", "").Replace(b)
+	if a == b {
+		return true
+	}
+
+	namesA, namesB := namedTypes(a), namedTypes(b)
+	if len(namesA) != len(namesB) {
+		return false
+	}
+	for i := range namesA {
+		x, y := namesA[i], namesB[i]
+		if x != y && !strings.HasSuffix(x, "/"+y) && !strings.HasSuffix(y, "/"+x) {
+			return false
+		}
+		a, b = strings.Replace(a, x, "", 1), strings.Replace(b, y, "", 1)
+	}
+
+	return a == b
+}
+
+// instantiate builds the concrete definition for an instantiation such as
+// pkg.Page[pkg.User] and returns its definition key. Unknown generic types,
+// wrong type argument counts and two instantiations given the same name
+// are recorded in instanceErrs.
+func (p *Parser) instantiate(raw string) string {
+	base, args, _ := splitGeneric(raw)
+	key := p.registerModel(instanceRaw(raw))
+	if p.instances == nil {
+		p.instances = make(map[string]string)
+	}
+	if owner, ok := p.instances[key]; ok {
+		if !sameInstance(owner, raw) {
+			p.instanceErrs = append(p.instanceErrs, fmt.Errorf("%s and %s are both instantiated as %s", owner, raw, key))
+		}
+		return key
+	}
+	p.instances[key] = raw
+	if _, ok := p.swagger.Definitions[key]; ok {
+		return key
+	}
+
+	def := &Schema{}
+	p.swagger.Definitions[key] = def
+
+	pType, ok := p.lookupType(base)
+	if !ok {
+		p.instanceErrs = append(p.instanceErrs, fmt.Errorf("cannot instantiate %s: could not find %s", raw, base))
+		return key
+	}
+
+	params := p.typeParams(base)
+	if len(params) != len(args) {
+		p.instanceErrs = append(p.instanceErrs, fmt.Errorf("cannot instantiate %s: %s expects %d type arguments, got %d", raw, base, len(params), len(args)))
+		return key
+	}
+
+	if pType.Doc != nil {
+		p.parseDefinitionOptions(def, pType.Doc.List)
+	}
+
+	inst := &instance{base: base, args: make(map[string]string, len(params))}
+	for i, param := range params {
+		inst.args[param] = args[i]
+	}
+
+	outer := p.instance
+	p.instance = inst
+	p.parseModel(key, def, pType)
+	p.instance = outer
+
+	return key
+}
+
+// instance is the instantiation of a generic type whose fields are being
+// walked, with the type argument of each type parameter.
+type instance struct {
+	base string
+	args map[string]string
+}
+
+// fieldType returns the type of a field of the generic type src with its
+// type parameters replaced by their arguments. It returns false when the
+// field does not mention a type parameter, or src is not the generic type
+// being instantiated.
+func (inst *instance) fieldType(src *structSource, field *ast.Field) (string, bool) {
+	if inst == nil || field == nil || fixPath(src.raw) != fixPath(inst.base) {
+		return "", false
+	}
+
+	return inst.substitute(field.Type, src.file, typePackage(src.raw))
+}
+
+func (inst *instance) substitute(expr ast.Expr, file *ast.File, pkgPath string) (string, bool) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if arg, ok := inst.args[t.Name]; ok {
+			return arg, true
+		}
+	case *ast.StarExpr:
+		raw, ok := inst.substitute(t.X, file, pkgPath)
+		return "*" + raw, ok
+	case *ast.ArrayType:
+		if t.Len == nil {
+			raw, ok := inst.substitute(t.Elt, file, pkgPath)
+			return "[]" + raw, ok
+		}
+	case *ast.MapType:
+		key, keyOK := inst.substitute(t.Key, file, pkgPath)
+		value, valueOK := inst.substitute(t.Value, file, pkgPath)
+		return "map[" + key + "]" + value, keyOK || valueOK
+	case *ast.IndexExpr:
+		raw, ok := inst.substitute(t.Index, file, pkgPath)
+		return exprTypeName(t.X, file, pkgPath) + "[" + raw + "]", ok
+	case *ast.IndexListExpr:
+		args, found := []string{}, false
+		for _, index := range t.Indices {
+			raw, ok := inst.substitute(index, file, pkgPath)
+			args = append(args, raw)
+			found = found || ok
+		}
+		return exprTypeName(t.X, file, pkgPath) + "[" + strings.Join(args, ",") + "]", found
+	}
+
+	return exprTypeName(expr, file, pkgPath), false
+}
+
+// typeSchema returns the schema for a Go type expression used as a type
+// argument.
+func (p *Parser) typeSchema(raw string) *Schema {
+	switch {
+	case strings.HasPrefix(raw, "[]"):
+		return &Schema{Type: "array", Items: p.typeSchema(raw[2:])}
+	case strings.HasPrefix(raw, "*"):
+		return p.typeSchema(raw[1:])
+	case strings.HasPrefix(raw, "map["):
+		if end := strings.Index(raw, "]"); end > 0 {
+			return &Schema{Type: "object", AdditionalProperties: p.typeSchema(raw[end+1:])}
+		}
+	}
+
+	if _, _, ok := splitGeneric(raw); ok {
+		return &Schema{Ref: "#/definitions/" + p.instantiate(raw)}
+	}
+
+	if typ, format, ok := getTypeFormat(raw); ok {
+		return &Schema{Type: typ, Format: format}
+	}
+
+	if mapped := p.mappedType(raw); mapped != nil {
+		def := &Schema{}
+		applyMappedType(def, mapped)
+		return def
+	}
+
+	key := p.registerModel(raw)
+	if _, ok := p.swagger.Definitions[key]; !ok {
+		def := &Schema{}
+		p.swagger.Definitions[key] = def
+		if pType, ok := p.lookupType(raw); ok {
+			if pType.Doc != nil {
+				p.parseDefinitionOptions(def, pType.Doc.List)
+				p.setModelName(key, p.parsePropertiesName(pType.Doc.List))
+			}
+			p.parseModel(key, def, pType)
+		} else {
+			logrus.Errorf("Could not find %s package", raw)
+		}
+	}
+
+	return &Schema{Ref: "#/definitions/" + key}
+}
@@ -0,0 +1,234 @@
+package spec
+
+import (
+	. "github.com/peak6/arlong/schema"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hasInlineStruct reports whether a field type declares a struct inline,
+// directly or as the element of a pointer, slice or map.
+func hasInlineStruct(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return true
+	case *ast.StarExpr:
+		return hasInlineStruct(t.X)
+	case *ast.ArrayType:
+		return hasInlineStruct(t.Elt)
+	case *ast.MapType:
+		return hasInlineStruct(t.Value)
+	}
+
+	return false
+}
+
+// applyInline replaces the walker's schema for a field with the one built
+// from source, keeping what the field annotations already set.
+func (p *Parser) applyInline(def *Schema, inline *Schema) {
+	description, enum, example := def.Description, def.Enum, def.Example
+	constraints := *def
+	*def = *inline
+	for _, c := range [][2]*int{
+		{&def.Maximum, &constraints.Maximum},
+		{&def.Minimum, &constraints.Minimum},
+		{&def.MaxLength, &constraints.MaxLength},
+		{&def.MinLength, &constraints.MinLength},
+		{&def.MaxItems, &constraints.MaxItems},
+		{&def.MinItems, &constraints.MinItems},
+	} {
+		if *c[1] != 0 {
+			*c[0] = *c[1]
+		}
+	}
+	if description != "" {
+		def.Description = description
+	}
+	if example != nil {
+		def.Example = example
+	}
+	if enum != nil {
+		def.Enum = enum
+	}
+}
+
+// importPath resolves a package name used in file to its import path.
+func importPath(file *ast.File, name string) string {
+	if file == nil {
+		return name
+	}
+
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return path
+			}
+			continue
+		}
+
+		if path == name || strings.HasSuffix(path, "/"+name) {
+			return path
+		}
+	}
+
+	return name
+}
+
+// exprTypeName returns the full type name of a type expression, e.g.
+// []github.com/org/models.User.
+func exprTypeName(expr ast.Expr, file *ast.File, pkgPath string) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if _, _, ok := getTypeFormat(t.Name); ok || pkgPath == "" {
+			return t.Name
+		}
+		return pkgPath + "." + t.Name
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			return importPath(file, x.Name) + "." + t.Sel.Name
+		}
+	case *ast.StarExpr:
+		return "*" + exprTypeName(t.X, file, pkgPath)
+	case *ast.ArrayType:
+		return "[]" + exprTypeName(t.Elt, file, pkgPath)
+	case *ast.MapType:
+		return "map[" + exprTypeName(t.Key, file, pkgPath) + "]" + exprTypeName(t.Value, file, pkgPath)
+	case *ast.IndexExpr:
+		return exprTypeName(t.X, file, pkgPath) + "[" + exprTypeName(t.Index, file, pkgPath) + "]"
+	case *ast.IndexListExpr:
+		args := []string{}
+		for _, index := range t.Indices {
+			args = append(args, exprTypeName(index, file, pkgPath))
+		}
+		return exprTypeName(t.X, file, pkgPath) + "[" + strings.Join(args, ",") + "]"
+	}
+
+	return ""
+}
+
+// exprSchema builds the schema of a type expression read from source. name
+// is the type name an inline struct gets when it is hoisted.
+func (p *Parser) exprSchema(expr ast.Expr, file *ast.File, pkgPath, name string) *Schema {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return p.structSchema(t, file, pkgPath, name)
+	case *ast.StarExpr:
+		def := p.exprSchema(t.X, file, pkgPath, name)
+		def.Nullable = true
+		return def
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (ident.Name == "byte" || ident.Name == "uint8") {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: p.exprSchema(t.Elt, file, pkgPath, name)}
+	case *ast.MapType:
+		return &Schema{Type: "object", AdditionalProperties: p.exprSchema(t.Value, file, pkgPath, name)}
+	case *ast.InterfaceType:
+		return &Schema{}
+	case *ast.Ident:
+		if t.Name == "any" {
+			return &Schema{}
+		}
+	}
+
+	raw := exprTypeName(expr, file, pkgPath)
+	if raw == "" {
+		return &Schema{}
+	}
+
+	return p.typeSchema(raw)
+}
+
+// structSchema builds an object schema for an inline struct type, reading
+// its fields the same way the model walker does.
+func (p *Parser) structSchema(st *ast.StructType, file *ast.File, pkgPath, name string) *Schema {
+	def := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	promote(def, p.sourceFields(st, file, pkgPath, name))
+	sort.Strings(def.Required)
+
+	if !p.HoistInline || name == "" {
+		return def
+	}
+
+	key := p.registerModel(name)
+	p.swagger.Definitions[key] = def
+
+	return &Schema{Ref: "#/definitions/" + key}
+}
+
+// sourceFields reads the fields of an inline struct type, and the fields
+// promoted from its untagged embedded structs.
+func (p *Parser) sourceFields(st *ast.StructType, file *ast.File, pkgPath, name string) []*jsonField {
+	fields := []*jsonField{}
+	for _, field := range st.Fields.List {
+		tags := reflect.StructTag("")
+		if field.Tag != nil {
+			if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tags = reflect.StructTag(tag)
+			}
+		}
+
+		embedded := len(field.Names) == 0
+		names := field.Names
+		if embedded {
+			names = []*ast.Ident{ast.NewIdent(embeddedName(field.Type))}
+		}
+
+		for _, ident := range names {
+			if !embedded && !ast.IsExported(ident.Name) {
+				continue
+			}
+
+			_, pointer := field.Type.(*ast.StarExpr)
+			spec := fieldSpec{
+				key:      ident.Name,
+				json:     tags.Get(`json`),
+				arlong:   tags.Get(`arlong`),
+				embedded: embedded,
+				pointer:  pointer,
+				nullable: nullableExpr(field.Type),
+			}
+			if field.Doc != nil {
+				spec.doc = field.Doc.List
+			}
+
+			jf, promote := p.readField(spec, func(prop *Schema) {
+				p.applyInline(prop, p.exprSchema(field.Type, file, pkgPath, name+ident.Name))
+			})
+
+			switch {
+			case promote:
+				fields = append(fields, p.sourceEmbedded(field.Type, file, pkgPath)...)
+			case jf != nil && (ast.IsExported(jf.name) || jf.tagged):
+				fields = append(fields, jf)
+			}
+		}
+	}
+
+	return fields
+}
+
+// sourceEmbedded returns the fields an untagged embedded field of an
+// inline struct contributes: the fields of a named struct one level
+// deeper, or a non-struct type under its type name.
+func (p *Parser) sourceEmbedded(expr ast.Expr, file *ast.File, pkgPath string) []*jsonField {
+	raw := strings.TrimPrefix(exprTypeName(expr, file, pkgPath), "*")
+	if pType, ok := p.lookupType(raw); ok && pType.Type == "struct" {
+		return p.embeddedFields(embeddedName(expr), pType, 0, map[string]bool{})
+	}
+
+	key := embeddedName(expr)
+	if !ast.IsExported(key) {
+		return nil
+	}
+
+	return []*jsonField{{name: key, schema: p.exprSchema(expr, file, pkgPath, "")}}
+}
@@ -0,0 +1,494 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// The annotation language:
+//
+//	line       = "//" { " " | "\t" } "@" tag [ values ] [ "\" ]
+//	values     = { space } { arg { space } }
+//	arg        = [ key "=" ] ( string | word )
+//	string     = `"` { char | `\"` | `\\` | `\n` | `\t` | `\r` } `"`
+//	word       = { char | group | string } with no space outside groups
+//	group      = ( "(" word ")" | "[" word "]" | "{" word "}" ) spaces allowed
+//
+// A line that ends with a backslash continues on the next comment line.
+// The indented lines after a @Description are more lines of its text. A
+// word such as {"id": 1, "tags": ["a"]} is kept as written, so JSON
+// literals can be given without quotes. Escapes are only read in quoted
+// strings; a backslash before any other character is kept.
+
+// SyntaxError is an annotation that does not follow the grammar.
+type SyntaxError struct {
+	// Position is set when the comment comes from a parsed file.
+	Position token.Position
+	// Column is the 1-based column in the comment line.
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Position.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+	}
+
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// arg is a value of an annotation, with its key when written as key=value.
+type arg struct {
+	key    string
+	value  string
+	quoted bool
+	json   bool
+	offset int
+	pos    token.Pos
+}
+
+// String writes a back in a form lexArgs reads as the same key and value.
+func (a *arg) String() string {
+	if a.key == "" {
+		return formatValue(a.value)
+	}
+
+	return a.key + "=" + formatValue(a.value)
+}
+
+// formatValue quotes v when it would not be read back as the same word,
+// so JSON literals and types such as []string are kept as they are.
+func formatValue(v string) string {
+	args, err := lexArgs(v)
+	if err == nil && len(args) == 1 && args[0].key == "" && !args[0].quoted && args[0].value == v && !strings.ContainsAny(v, "\\\n\r") {
+		return v
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(v) + `"`
+}
+
+func isJSONLiteral(v string) bool {
+	return (strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[")) && json.Valid([]byte(v))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// lexError is a syntax error at an offset of the lexed text.
+type lexError struct {
+	offset int
+	msg    string
+}
+
+// lexArgs splits the values of an annotation into args.
+func lexArgs(s string) ([]*arg, *lexError) {
+	args := []*arg{}
+	i := 0
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return args, nil
+		}
+
+		a := &arg{offset: i}
+		if k := keyEnd(s, i); k > i && k < len(s) && s[k] == '=' {
+			a.key = s[i:k]
+			i = k + 1
+		}
+
+		var err *lexError
+		if i < len(s) && s[i] == '"' {
+			a.quoted = true
+			a.value, i, err = lexString(s, i)
+			if err == nil && i < len(s) && !isSpace(s[i]) {
+				err = &lexError{i, fmt.Sprintf("unexpected %q after a quoted value", s[i])}
+			}
+		} else {
+			a.value, i, err = lexWord(s, i)
+			a.json = isJSONLiteral(a.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, a)
+	}
+}
+
+// keyEnd returns where a key starting at i would end.
+func keyEnd(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case isSpace(c), strings.IndexByte(`="(){}[]`, c) >= 0:
+			return i
+		}
+	}
+
+	return i
+}
+
+// lexString reads the quoted string starting at i and returns its value
+// and the offset after the closing quote.
+func lexString(s string, i int) (string, int, *lexError) {
+	start := i
+	b := []byte{}
+	for i++; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return string(b), i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, &lexError{i, "unterminated escape"}
+			}
+			i++
+			switch s[i] {
+			case '"', '\\':
+				b = append(b, s[i])
+			case 'n':
+				b = append(b, '\n')
+			case 't':
+				b = append(b, '\t')
+			case 'r':
+				b = append(b, '\r')
+			default:
+				b = append(b, '\\', s[i])
+			}
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return "", 0, &lexError{start, "unterminated string"}
+}
+
+// lexWord reads the word starting at i and returns it as written and the
+// offset after it. Spaces are part of the word inside brackets.
+func lexWord(s string, i int) (string, int, *lexError) {
+	start := i
+	open := []int{}
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case isSpace(c) && len(open) == 0:
+			return s[start:i], i, nil
+		case c == '(' || c == '[' || c == '{':
+			open = append(open, i)
+		case c == ')' || c == ']' || c == '}':
+			if len(open) == 0 {
+				continue
+			}
+			last := open[len(open)-1]
+			if closing(s[last]) != c {
+				return "", 0, &lexError{i, fmt.Sprintf("%q does not close %q", c, s[last])}
+			}
+			open = open[:len(open)-1]
+		case c == '"':
+			_, end, err := lexString(s, i)
+			if err != nil {
+				return "", 0, err
+			}
+			i = end - 1
+		}
+	}
+
+	if len(open) > 0 {
+		last := open[len(open)-1]
+		return "", 0, &lexError{last, fmt.Sprintf("unclosed %q", s[last])}
+	}
+
+	return s[start:], i, nil
+}
+
+func closing(c byte) byte {
+	switch c {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	}
+
+	return '}'
+}
+
+// segment maps a part of an annotation's text back to its comment line.
+type segment struct {
+	offset  int
+	comment *ast.Comment
+	column  int
+}
+
+// annotation is an @tag line of a comment, with its continuation lines.
+type annotation struct {
+	tag      string
+	text     string
+	pos      token.Pos
+	comment  *ast.Comment
+	segments []segment
+	fset     *token.FileSet
+	lexed    []*arg
+}
+
+// annotationStart returns the offsets of the tag and of the first byte
+// after it, or -1 when the comment is not an annotation.
+func annotationStart(text string) (int, int) {
+	i := 0
+	for i < len(text) && (text[i] == '/' || text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	if i >= len(text) || text[i] != '@' || !strings.HasPrefix(text, "//") {
+		return -1, -1
+	}
+
+	end := i
+	for end < len(text) && !isSpace(text[end]) {
+		end++
+	}
+
+	return i, end
+}
+
+// isTerminator reports whether comment is the bare // that ends a block.
+func isTerminator(comment *ast.Comment) bool {
+	return strings.TrimSpace(comment.Text) == "//"
+}
+
+// newAnnotation lexes the tag of comment. It returns nil when the comment
+// is not an annotation.
+func newAnnotation(fset *token.FileSet, comment *ast.Comment) *annotation {
+	start, end := annotationStart(comment.Text)
+	if start < 0 {
+		return nil
+	}
+
+	a := &annotation{tag: comment.Text[start:end], pos: comment.Pos() + token.Pos(start), comment: comment, fset: fset}
+	a.appendText(comment, end)
+
+	return a
+}
+
+// appendText adds the text of comment from column on.
+func (a *annotation) appendText(comment *ast.Comment, column int) {
+	for column < len(comment.Text) && isSpace(comment.Text[column]) {
+		column++
+	}
+
+	a.add(comment, column, " ")
+}
+
+// appendLine adds the text of comment from column on as a new line, keeping
+// its indentation.
+func (a *annotation) appendLine(comment *ast.Comment, column int) {
+	a.add(comment, column, "\n")
+}
+
+func (a *annotation) add(comment *ast.Comment, column int, sep string) {
+	if a.text != "" {
+		a.text += sep
+	}
+	a.segments = append(a.segments, segment{offset: len(a.text), comment: comment, column: column})
+	a.text += strings.TrimRight(comment.Text[column:], " \t\r\n")
+}
+
+// indentation returns the offset of the text of an indented comment line
+// and how far it is indented, or -1 when the line is not indented.
+func indentation(comment *ast.Comment) (int, int) {
+	text := comment.Text
+	start := strings.Index(text, "//") + 2
+	if start < 2 || strings.TrimSpace(text[start:]) == "" {
+		return -1, -1
+	}
+
+	i := start
+	if i < len(text) && text[i] == ' ' {
+		i++
+	}
+	if i >= len(text) || (text[i] != ' ' && text[i] != '\t') {
+		return -1, -1
+	}
+
+	indent := i
+	for indent < len(text) && (text[indent] == ' ' || text[indent] == '\t') {
+		indent++
+	}
+
+	return start, indent - start
+}
+
+// continues reports whether the text ends with a continuation backslash and
+// removes it.
+func (a *annotation) continues() bool {
+	n := 0
+	for n < len(a.text) && a.text[len(a.text)-1-n] == '\\' {
+		n++
+	}
+	if n%2 == 0 {
+		return false
+	}
+
+	a.text = strings.TrimRight(a.text[:len(a.text)-1], " \t")
+	return true
+}
+
+// locate returns the position and the 1-based column of an offset of the
+// text.
+func (a *annotation) locate(offset int) (token.Pos, int) {
+	seg := a.segments[0]
+	for _, s := range a.segments {
+		if s.offset <= offset {
+			seg = s
+		}
+	}
+
+	column := seg.column + offset - seg.offset
+	return seg.comment.Pos() + token.Pos(column), column + 1
+}
+
+// errorAt returns a SyntaxError for an offset of the text.
+func (a *annotation) errorAt(offset int, msg string) *SyntaxError {
+	pos, column := a.locate(offset)
+	err := &SyntaxError{Column: column, Msg: fmt.Sprintf("%s: %s", a.tag, msg)}
+	if a.fset != nil && pos.IsValid() && a.fset.File(pos) != nil {
+		err.Position = a.fset.Position(pos)
+	}
+
+	return err
+}
+
+// args lexes the values of the annotation. Syntax errors panic with a
+// *SyntaxError, which Parse returns.
+func (a *annotation) args() []*arg {
+	if a.lexed != nil {
+		return a.lexed
+	}
+
+	args, err := lexArgs(a.text)
+	if err != nil {
+		panic(a.errorAt(err.offset, err.msg))
+	}
+	for _, arg := range args {
+		arg.pos, _ = a.locate(arg.offset)
+	}
+	a.lexed = args
+
+	return args
+}
+
+// rest returns the text from the nth arg on, as written.
+func (a *annotation) rest(n int) string {
+	args := a.args()
+	if n >= len(args) {
+		return ""
+	}
+
+	return a.text[args[n].offset:]
+}
+
+// values returns the args from the nth on as a map. Values without a key
+// are stored as keys with an empty value, e.g. required.
+func (a *annotation) values(n int) map[string]string {
+	result := map[string]string{}
+	args := a.args()
+	if n >= len(args) {
+		return result
+	}
+
+	for _, arg := range args[n:] {
+		if arg.key == "" {
+			result[arg.value] = ""
+		} else {
+			result[arg.key] = arg.value
+		}
+	}
+
+	return result
+}
+
+// offset returns the offset of the arg with key, or 0 when there is none.
+func (a *annotation) offset(key string) int {
+	for _, arg := range a.args() {
+		if arg.key == key {
+			return arg.offset
+		}
+	}
+
+	return 0
+}
+
+// word returns the value of the nth arg, or "" when there is none.
+func (a *annotation) word(n int) string {
+	if args := a.args(); n < len(args) {
+		return args[n].value
+	}
+
+	return ""
+}
+
+// annotationAt lexes the annotation on comments[i] with its continuation
+// lines and returns the index of its last line. It returns nil when the
+// comment is not an annotation.
+func (p *Parser) annotationAt(comments []*ast.Comment, i int) (*annotation, int) {
+	ann := newAnnotation(p.fset, comments[i])
+	if ann == nil {
+		return nil, i
+	}
+
+	for ann.continues() && i+1 < len(comments) && !isTerminator(comments[i+1]) {
+		i++
+		ann.appendText(comments[i], strings.Index(comments[i].Text, "//")+2)
+	}
+
+	if strings.EqualFold(ann.tag, "@Description") {
+		i = ann.appendIndented(comments, i)
+	}
+
+	return ann, i
+}
+
+// appendIndented adds the indented comment lines after comments[i] as new
+// lines of the text, without the indentation they share, and returns the
+// index of the last one.
+func (a *annotation) appendIndented(comments []*ast.Comment, i int) int {
+	last, margin := i, -1
+	for j := i + 1; j < len(comments); j++ {
+		start, indent := indentation(comments[j])
+		if tag, _ := annotationStart(comments[j].Text); start < 0 || tag >= 0 {
+			break
+		}
+		if margin < 0 || indent < margin {
+			margin = indent
+		}
+		last = j
+	}
+
+	for j := i + 1; j <= last; j++ {
+		start, _ := indentation(comments[j])
+		a.appendLine(comments[j], start+margin)
+	}
+
+	return last
+}
+
+// annotations lexes the annotations of comments. With block set it stops
+// at the bare // that ends a block and also returns its index, else it
+// returns len(comments).
+func (p *Parser) annotations(comments []*ast.Comment, block bool) ([]*annotation, int) {
+	anns := []*annotation{}
+	for i := 0; i < len(comments); i++ {
+		if block && isTerminator(comments[i]) {
+			return anns, i
+		}
+
+		var ann *annotation
+		if ann, i = p.annotationAt(comments, i); ann != nil {
+			anns = append(anns, ann)
+		}
+	}
+
+	return anns, len(comments)
+}
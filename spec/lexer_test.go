@@ -0,0 +1,233 @@
+package spec
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func argStrings(args []*arg) []string {
+	result := []string{}
+	for _, a := range args {
+		result = append(result, a.key+"|"+a.value)
+	}
+
+	return result
+}
+
+func TestLexArgs(t *testing.T) {
+	for text, want := range map[string][]string{
+		`name=id in=path required`:              {"name|id", "in|path", "|required"},
+		"a=1\tb=2  c":                           {"a|1", "b|2", "|c"},
+		`url=http://x.com/?a=b&c=d`:             {"url|http://x.com/?a=b&c=d"},
+		`desc="Say \"hi\"\n\\ \d"`:              {"desc|Say \"hi\"\n\\ \\d"},
+		`desc=""`:                               {"desc|"},
+		`key=`:                                  {"key|"},
+		`example={"id": 1, "tags": ["a", "b"]}`: {`example|{"id": 1, "tags": ["a", "b"]}`},
+		`enums(a, b) default("x y")`:            {"|enums(a, b)", `|default("x y")`},
+		`{object} pkg.Page{data=[]pkg.User}`:    {"|{object}", "|pkg.Page{data=[]pkg.User}"},
+		`a\b c`:                                 {`|a\b`, "|c"},
+	} {
+		args, err := lexArgs(text)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", text, err.msg)
+			continue
+		}
+		if got := argStrings(args); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s: expected %q, got %q", text, want, got)
+		}
+	}
+
+	args, _ := lexArgs(`a "b" c={"x": 1}`)
+	if args[0].quoted || !args[1].quoted || !args[2].json || args[2].offset != 6 {
+		t.Errorf("unexpected flags %#v %#v %#v", args[0], args[1], args[2])
+	}
+}
+
+func TestLexArgsErrors(t *testing.T) {
+	for text, want := range map[string]struct {
+		offset int
+		msg    string
+	}{
+		`desc="open`:   {5, "unterminated string"},
+		`desc="a\`:     {7, "unterminated escape"},
+		`a=(b c`:       {2, `unclosed '('`},
+		`x [a}`:        {4, `'}' does not close '['`},
+		`desc="a"b c`:  {8, `unexpected 'b' after a quoted value`},
+		`ok {"a": "}`:  {9, "unterminated string"},
+		`ok=1 bad=(x]`: {11, `']' does not close '('`},
+	} {
+		_, err := lexArgs(text)
+		if err == nil || err.offset != want.offset || err.msg != want.msg {
+			t.Errorf("%s: expected %d %q, got %+v", text, want.offset, want.msg, err)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for val, want := range map[string]string{
+		"plain":           "plain",
+		"[]string":        "[]string",
+		`{"a": [1, 2]}`:   `{"a": [1, 2]}`,
+		"two words":       `"two words"`,
+		"a=b":             `"a=b"`,
+		"":                `""`,
+		`say "hi"`:        `"say \"hi\""`,
+		"line\nbreak":     `"line\nbreak"`,
+		`C:\path`:         `"C:\\path"`,
+		"(open":           `"(open"`,
+		"http://x.com?a=": `"http://x.com?a="`,
+	} {
+		if got := formatValue(val); got != want {
+			t.Errorf("%q: expected %s, got %s", val, want, got)
+		}
+	}
+}
+
+const lexerSource = `package p
+
+// @Path /users/{id}
+// @Method GET
+// @Param name=id in=path \
+//     type=integer desc="The id"
+// @Response 200 desc="OK" \\
+// @Param name=(broken
+//
+type T struct{}
+`
+
+func TestAnnotations(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "lexer.go", lexerSource, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.fset = fset
+	comments := file.Comments[0].List
+	anns, end := parser.annotations(comments, true)
+	if len(anns) != 5 || end != 6 {
+		t.Fatalf("expected 5 annotations ending at 6, got %d ending at %d", len(anns), end)
+	}
+
+	param := anns[2]
+	if param.tag != "@Param" || param.text != `name=id in=path type=integer desc="The id"` {
+		t.Errorf("unexpected continued annotation %q %q", param.tag, param.text)
+	}
+	if vals := param.values(0); vals["type"] != "integer" || vals["desc"] != "The id" {
+		t.Errorf("unexpected values %v", vals)
+	}
+	if pos := fset.Position(param.args()[2].pos); pos.Line != 6 || pos.Column != 8 {
+		t.Errorf("expected type= at 6:8, got %s", pos)
+	}
+
+	if resp := anns[3]; resp.text != `200 desc="OK" \\` || len(resp.args()) != 3 {
+		t.Errorf("an escaped backslash should not continue the line: %q", resp.text)
+	}
+
+	func() {
+		defer func() {
+			err, ok := recover().(*SyntaxError)
+			if !ok {
+				t.Fatalf("expected a *SyntaxError, got %v", err)
+			}
+			if err.Position.Line != 8 || err.Position.Column != 16 || err.Column != 16 {
+				t.Errorf("unexpected error position %s column %d", err.Position, err.Column)
+			}
+			if want := `lexer.go:8:16: @Param: unclosed '('`; err.Error() != want {
+				t.Errorf("expected %q, got %q", want, err.Error())
+			}
+		}()
+		anns[4].args()
+	}()
+
+	ann := newAnnotation(nil, &ast.Comment{Text: `//	@Tag  user desc=x \`})
+	if ann.tag != "@Tag" || ann.word(0) != "user" || ann.rest(1) != `desc=x \` {
+		t.Errorf("unexpected annotation %q %q", ann.tag, ann.text)
+	}
+	if newAnnotation(nil, &ast.Comment{Text: "// plain text @Tag"}) != nil {
+		t.Error("text before the tag should not be an annotation")
+	}
+}
+
+func FuzzLexArgs(f *testing.F) {
+	for _, seed := range []string{
+		`name=id in=path required "The id"`,
+		`desc="Say \"hi\"\n" url=http://x?a=b`,
+		`example={"id": 1, "tags": ["a"]} enums(a, b)`,
+		`{object} pkg.Page{data=[]pkg.User} "OK"`,
+		"a\tb=\\c =d",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		args, err := lexArgs(text)
+		if err != nil {
+			if err.offset < 0 || err.offset > len(text) {
+				t.Fatalf("error offset %d outside %q", err.offset, text)
+			}
+			return
+		}
+
+		written := []string{}
+		for _, a := range args {
+			if a.offset < 0 || a.offset >= len(text) {
+				t.Fatalf("offset %d outside %q", a.offset, text)
+			}
+			written = append(written, a.String())
+		}
+
+		again, err := lexArgs(strings.Join(written, " "))
+		if err != nil {
+			t.Fatalf("%q written as %q: %s", text, written, err.msg)
+		}
+		if got, want := argStrings(again), argStrings(args); strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+			t.Fatalf("%q written as %q read back as %q, expected %q", text, written, got, want)
+		}
+	})
+}
+
+func FuzzAnnotations(f *testing.F) {
+	f.Add("@Param name=id \\", "  in=path desc=\"x\"")
+	f.Add("@Response 200 {object} pkg.User", "@Example 200 json {\"a\": 1}")
+	f.Add("\t@Tag (", "]")
+
+	f.Fuzz(func(t *testing.T, first, second string) {
+		if strings.ContainsAny(first+second, "\n\r") {
+			return
+		}
+
+		comments := []*ast.Comment{
+			{Slash: 1, Text: "// " + first},
+			{Slash: token.Pos(5 + len(first)), Text: "// " + second},
+		}
+		end := comments[1].End()
+
+		defer func() {
+			if r := recover(); r != nil {
+				err, ok := r.(*SyntaxError)
+				if !ok {
+					panic(r)
+				}
+				if err.Column < 1 || err.Column > len(comments[1].Text)+len(comments[0].Text) {
+					t.Fatalf("column %d outside the comments", err.Column)
+				}
+			}
+		}()
+
+		anns, _ := NewParser("").annotations(comments, false)
+		for _, ann := range anns {
+			for _, a := range ann.args() {
+				if a.pos < comments[0].Pos() || a.pos >= end {
+					t.Fatalf("%q: pos %d outside the comments", a.value, a.pos)
+				}
+			}
+			ann.values(0)
+			ann.rest(1)
+		}
+	})
+}
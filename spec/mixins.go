@@ -0,0 +1,135 @@
+package spec
+
+import (
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// operationTags are the annotations of an operation, which a mixin may hold.
+var operationTags = map[string]bool{
+	"@Consumes":     true,
+	"@Produces":     true,
+	"@Summary":      true,
+	"@Description":  true,
+	"@Deprecated":   true,
+	"@Schemes":      true,
+	"@OperationId":  true,
+	"@Security":     true,
+	"@Tags":         true,
+	"@ExternalDocs": true,
+	"@Param":        true,
+	"@Example":      true,
+	"@Response":     true,
+	"@Use":          true,
+}
+
+// mixin is a named list of operation annotations that @Use applies.
+type mixin struct {
+	decl *annotation
+	anns []*annotation
+	used bool
+}
+
+// readMixins reads the @Mixin blocks of comments.
+func (p *Parser) readMixins(comments []*ast.Comment) {
+	for i := 0; i < len(comments); i++ {
+		ann, _ := p.annotationAt(comments, i)
+		if ann != nil && ann.tag == "@Mixin" {
+			i += p.parseMixin(comments[i:])
+		}
+	}
+}
+
+func (p *Parser) parseMixin(comments []*ast.Comment) int {
+	anns, n := p.annotations(comments, true)
+	decl := anns[0]
+	name := decl.word(0)
+	if name == "" || len(decl.args()) > 1 {
+		panic(decl.errorAt(0, "expected a mixin name"))
+	}
+	if m, ok := p.mixins[name]; ok {
+		msg := fmt.Sprintf("mixin %s is declared twice", name)
+		if prev := m.decl.errorAt(0, ""); prev.Position.IsValid() {
+			msg = fmt.Sprintf("mixin %s is already declared at %s", name, prev.Position)
+		}
+		panic(decl.errorAt(0, msg))
+	}
+
+	for _, ann := range anns[1:] {
+		if !operationTags[ann.tag] {
+			panic(ann.errorAt(0, "only operation annotations can be used in a mixin"))
+		}
+	}
+
+	if p.mixins == nil {
+		p.mixins = make(map[string]*mixin)
+	}
+	p.mixins[name] = &mixin{decl: decl, anns: anns[1:]}
+
+	return n
+}
+
+// useMixins applies the mixins named by a @Use annotation to method, in
+// the order they are named, as if their annotations were written in place
+// of the @Use. chain holds the mixins being applied, to report cycles.
+func (p *Parser) useMixins(method *Operation, use *annotation, chain []string) {
+	if len(use.args()) == 0 {
+		panic(use.errorAt(0, "expected mixin names"))
+	}
+
+	for _, a := range use.args() {
+		name := a.value
+		m, ok := p.mixins[name]
+		if !ok || a.key != "" {
+			panic(use.errorAt(a.offset, fmt.Sprintf("unknown mixin %s", a)))
+		}
+		for _, used := range chain {
+			if used == name {
+				panic(use.errorAt(a.offset, fmt.Sprintf("mixin cycle %s -> %s", strings.Join(chain, " -> "), name)))
+			}
+		}
+
+		m.used = true
+		p.inMixin++
+		for _, ann := range m.anns {
+			if ann.tag == "@Use" {
+				p.useMixins(method, ann, append(chain, name))
+				continue
+			}
+			p.parseOperation(method, ann)
+		}
+		p.inMixin--
+	}
+}
+
+// checkMixins warns about mixins that no operation uses.
+func (p *Parser) checkMixins() {
+	names := []string{}
+	for name, m := range p.mixins {
+		if !m.used {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		logrus.Warnf("Mixin %s is never used", name)
+	}
+}
+
+// setParameter adds param to params, replacing a parameter with the same
+// name and location, or the same $ref.
+func setParameter(params []*Parameter, param *Parameter) []*Parameter {
+	for i, old := range params {
+		if param.Ref != "" && old.Ref == param.Ref || param.Ref == "" && old.Ref == "" && old.Name == param.Name && old.In == param.In {
+			params[i] = param
+			return params
+		}
+	}
+
+	return append(params, param)
+}
@@ -0,0 +1,216 @@
+package spec
+
+import (
+	"fmt"
+	. "github.com/peak6/arlong/schema"
+	"sort"
+	"strings"
+)
+
+// NamingStrategy decides how Go types are named in the definitions section.
+type NamingStrategy int
+
+const (
+	// NamingFullPath keys definitions by import path and type name,
+	// e.g. github.com.org.svc.models.User.
+	NamingFullPath NamingStrategy = iota
+	// NamingPackage keys definitions by package and type name, e.g. models.User.
+	NamingPackage
+	// NamingShort keys definitions by the bare type name, e.g. User.
+	NamingShort
+)
+
+func ParseNamingStrategy(s string) (NamingStrategy, error) {
+	switch s {
+	case "", "full":
+		return NamingFullPath, nil
+	case "package", "pkg":
+		return NamingPackage, nil
+	case "short":
+		return NamingShort, nil
+	}
+
+	return NamingFullPath, fmt.Errorf("unknown naming strategy %q", s)
+}
+
+type modelName struct {
+	raw    string
+	custom string
+}
+
+// registerModel records that the definition key belongs to the Go type raw
+// (import/path.Type) so that it can be renamed once all models are known.
+func (p *Parser) registerModel(raw string) string {
+	key := fixPath(raw)
+	model, ok := p.models[key]
+	if !ok {
+		model = &modelName{}
+		p.models[key] = model
+	}
+
+	// nested parsetype names are rewritten in place, so keep the first raw
+	// form that still carries the import path
+	if model.raw == "" || (!strings.Contains(model.raw, "/") && strings.Contains(raw, "/")) {
+		model.raw = raw
+	}
+
+	return key
+}
+
+func (p *Parser) setModelName(key, name string) {
+	if name == "" {
+		return
+	}
+
+	if model, ok := p.models[key]; ok {
+		model.custom = name
+	}
+}
+
+// splitTypeName splits import/path.Type into its path segments and type name.
+func splitTypeName(raw string) ([]string, string) {
+	index := strings.LastIndex(raw, ".")
+	if index < 0 {
+		return nil, raw
+	}
+
+	pkg := raw[:index]
+	if !strings.Contains(pkg, "/") {
+		// already flattened by fixPath, the package is the last segment
+		segments := strings.Split(pkg, ".")
+		return segments[len(segments)-1:], raw[index+1:]
+	}
+
+	return strings.Split(pkg, "/"), raw[index+1:]
+}
+
+// qualifiedName returns the type name prefixed by the last depth package
+// segments.
+func qualifiedName(segments []string, typeName string, depth int) string {
+	if depth > len(segments) {
+		depth = len(segments)
+	}
+
+	return strings.Join(append(append([]string{}, segments[len(segments)-depth:]...), typeName), ".")
+}
+
+func (p *Parser) modelDepth(segments []string) int {
+	switch p.Naming {
+	case NamingShort:
+		return 0
+	case NamingPackage:
+		return 1
+	}
+
+	return len(segments)
+}
+
+// resolveDefinitionNames renames every Go type definition according to the
+// naming strategy and rewrites all references to it. Types that end up with
+// the same name are qualified with more of their package path, in sorted
+// order, until every name is unique. A custom @Name that collides falls back
+// to the strategy name first. Names that stay ambiguous are an error.
+func (p *Parser) resolveDefinitionNames() error {
+	keys := []string{}
+	for key := range p.models {
+		if _, ok := p.swagger.Definitions[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	taken := map[string]struct{}{}
+	for key := range p.swagger.Definitions {
+		if _, ok := p.models[key]; !ok {
+			taken[key] = struct{}{}
+		}
+	}
+
+	depths := map[string]int{}
+	names := map[string]string{}
+	for _, key := range keys {
+		model := p.models[key]
+		segments, typeName := splitTypeName(model.raw)
+		if model.custom != "" {
+			depths[key] = -1
+			names[key] = model.custom
+			continue
+		}
+		depths[key] = p.modelDepth(segments)
+		names[key] = qualifiedName(segments, typeName, depths[key])
+		if p.Naming == NamingFullPath {
+			names[key] = key
+		}
+	}
+
+	for {
+		groups := map[string][]string{}
+		for _, key := range keys {
+			groups[names[key]] = append(groups[names[key]], key)
+		}
+
+		changed := false
+		for _, key := range keys {
+			name := names[key]
+			_, clash := taken[name]
+			if len(groups[name]) < 2 && !clash {
+				continue
+			}
+
+			segments, typeName := splitTypeName(p.models[key].raw)
+			if depths[key] >= len(segments) {
+				continue
+			}
+
+			if depths[key] < 0 {
+				// a custom name collided, fall back to the strategy name
+				depths[key] = p.modelDepth(segments)
+			} else {
+				depths[key]++
+			}
+			names[key] = qualifiedName(segments, typeName, depths[key])
+			changed = true
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	owners := map[string]string{}
+	for key := range taken {
+		owners[key] = key
+	}
+	for _, key := range keys {
+		if owner, ok := owners[names[key]]; ok {
+			return fmt.Errorf("definition name %s is used by both %s and %s", names[key], owner, p.models[key].raw)
+		}
+		owners[names[key]] = p.models[key].raw
+	}
+
+	renames := map[string]string{}
+	defs := make(map[string]*Schema, len(p.swagger.Definitions))
+	for key, def := range p.swagger.Definitions {
+		name, ok := names[key]
+		if !ok {
+			defs[key] = def
+			continue
+		}
+
+		defs[name] = def
+		renames[key] = name
+	}
+	p.swagger.Definitions = defs
+
+	walkSwaggerSchemas(p.swagger, func(s *Schema) {
+		if !strings.HasPrefix(s.Ref, "#/definitions/") {
+			return
+		}
+
+		if name, ok := renames[removeDefinitionRef(s.Ref)]; ok {
+			s.Ref = "#/definitions/" + name
+		}
+	})
+
+	return nil
+}
@@ -2,25 +2,65 @@ package spec
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/Sirupsen/logrus"
 	. "github.com/peak6/arlong/schema"
 	"github.com/peak6/utils/parsetype"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 type Parser struct {
+	// Naming selects how Go types are named in the definitions section.
+	Naming NamingStrategy
+	// Prune drops definitions, parameters, responses and security
+	// definitions that cannot be reached from the paths or Roots.
+	Prune bool
+	// Roots are extra definition names that are always kept when pruning.
+	Roots []string
+	// TypeMap maps Go types (import/path.Type or pkg.Type) to the schema
+	// used in their place. @TypeMap annotations are added to it.
+	TypeMap map[string]*Schema
+	// InferRequired marks struct fields required unless they are pointers
+	// or tagged omitempty.
+	InferRequired bool
+	// HoistInline moves inline struct types into definitions named after
+	// the parent type and field, e.g. UserAddress.
+	HoistInline bool
+	// Examples synthesizes an example for every definition and response
+	// that has none.
+	Examples bool
+
 	swagger         *Swagger
+	fset            *token.FileSet
 	packages        []*ast.Package
 	usedDefinitions []*Schema
-	usedParameters  []string
-	usedResponses   []string
+	usedParameters  []*reference
+	usedResponses   []*reference
+	models          map[string]*modelName
+	types           *parsetype.Parser
+	generics        map[string][]string
+	walking         []*walkFrame
+	cycles          []string
+	sources         map[string][]*ast.File
+	mixins          map[string]*mixin
+	mixinDocs       map[*Operation]bool
+	instances       map[string]string
+	instanceErrs    []error
+	inMixin         int
+	instance        *instance
+	unused          *Unused
 	basePkgPath     string
 	json            []byte
+	file            *ast.File
+	pkgPath         string
 }
 
 func NewParser(basePkgPath string) *Parser {
@@ -30,11 +70,32 @@ func NewParser(basePkgPath string) *Parser {
 	}
 }
 
-func (p *Parser) Parse() error {
+func (p *Parser) Parse() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntax, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			p.swagger, err = nil, syntax
+		}
+	}()
+
 	p.swagger = New()
+	p.fset = token.NewFileSet()
 	p.usedDefinitions = []*Schema{}
-	p.usedParameters = []string{}
-	p.usedResponses = []string{}
+	p.usedParameters = []*reference{}
+	p.usedResponses = []*reference{}
+	p.models = make(map[string]*modelName)
+	p.generics = make(map[string][]string)
+	p.walking = nil
+	p.cycles = nil
+	p.sources = make(map[string][]*ast.File)
+	p.mixins = nil
+	p.mixinDocs = nil
+	p.inMixin = 0
+	p.instances = nil
+	p.instanceErrs = nil
 	p.json = nil
 
 	if err := p.parsePackages(); err != nil {
@@ -43,16 +104,40 @@ func (p *Parser) Parse() error {
 
 	p.parseComments()
 	p.parseDefinitionModels()
+	if len(p.instanceErrs) > 0 {
+		p.swagger = nil
+		return p.instanceErrs[0]
+	}
+	if err := p.resolveDefinitionNames(); err != nil {
+		p.swagger = nil
+		return err
+	}
+	p.typeExamples()
 	// p.mergeAll()
 	p.validate()
+	if problems := p.validateExamples(); len(problems) > 0 {
+		p.swagger = nil
+		return fmt.Errorf("%d invalid examples", len(problems))
+	}
+
+	p.unused = p.findUnused()
+	if p.Prune {
+		p.prune(p.unused)
+	}
+
+	if p.Examples {
+		p.swagger.FillExamples()
+	}
 
 	return nil
 }
 
 func (p *Parser) JSON() ([]byte, error) {
 	if p.json == nil {
-		if err := p.Parse(); err != nil {
-			return nil, err
+		if p.swagger == nil {
+			if err := p.Parse(); err != nil {
+				return nil, err
+			}
 		}
 
 		result, err := json.Marshal(p.swagger)
@@ -66,11 +151,25 @@ func (p *Parser) JSON() ([]byte, error) {
 	return p.json, nil
 }
 
+// Swagger returns the parsed document, parsing the packages first if needed.
+func (p *Parser) Swagger() (*Swagger, error) {
+	if p.swagger == nil {
+		if err := p.Parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.swagger, nil
+}
+
 func (p *Parser) parsePackages() error {
 	return filepath.Walk(p.basePkgPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
 		if info.IsDir() {
-			fset := token.NewFileSet()
-			packages, err := parser.ParseDir(fset, path, nil, parser.ParseComments)
+			packages, err := parser.ParseDir(p.fset, path, nil, parser.ParseComments)
 			if err != nil {
 				return err
 			}
@@ -84,28 +183,62 @@ func (p *Parser) parsePackages() error {
 	})
 }
 
-func (p *Parser) parseComments() {
-	for _, pack := range p.packages {
+// packageSource parses the Go files of an import path, resolved from the
+// base package, for the information the type walker does not keep.
+func (p *Parser) packageSource(pkgPath string) []*ast.File {
+	if files, ok := p.sources[pkgPath]; ok {
+		return files
+	}
+
+	files := []*ast.File{}
+	p.sources[pkgPath] = files
+
+	pkg, err := build.Import(pkgPath, p.basePkgPath, build.FindOnly)
+	if err != nil {
+		return files
+	}
+
+	packages, err := parser.ParseDir(token.NewFileSet(), pkg.Dir, nil, parser.ParseComments)
+	if err != nil {
+		logrus.Errorf("Could not parse %s package: %s", pkgPath, err)
+		return files
+	}
+
+	for _, pack := range packages {
 		for _, f := range pack.Files {
-			for i := 0; i < len(f.Comments); i++ {
-				p.readZone(f.Comments[i].List)
+			files = append(files, f)
+		}
+	}
+	p.sources[pkgPath] = files
+
+	return files
+}
+
+// parseComments reads the annotations of every file. Mixins are read first
+// so that @Use can refer to mixins declared anywhere.
+func (p *Parser) parseComments() {
+	for _, read := range []func([]*ast.Comment){p.readMixins, p.readZone} {
+		for _, pack := range p.packages {
+			for _, f := range pack.Files {
+				p.file, p.pkgPath = f, p.filePackage(f)
+				for i := 0; i < len(f.Comments); i++ {
+					read(f.Comments[i].List)
+				}
 			}
 		}
 	}
+	p.checkMixins()
 }
 
 func (p *Parser) parseDefinitionModels() {
 	parser := parsetype.NewParser()
+	p.types = parser
 
 	packNames := make(map[string]struct{})
 	for _, val := range p.usedDefinitions {
-		index := strings.LastIndex(val.RawRefName, ".")
-		data := val.RawRefName
-		if index > 0 {
-			data = val.RawRefName[:index]
+		for _, name := range namedTypes(val.RawRefName) {
+			packNames[typePackage(name)] = struct{}{}
 		}
-
-		packNames[data] = struct{}{}
 	}
 
 	for key := range packNames {
@@ -114,6 +247,18 @@ func (p *Parser) parseDefinitionModels() {
 
 	parser.MergeComposite()
 	for _, val := range p.usedDefinitions {
+		if _, _, ok := splitGeneric(val.RawRefName); ok {
+			p.instantiate(val.RawRefName)
+			continue
+		}
+
+		if _, ok := p.swagger.Definitions[definitionKey(val.RawRefName)]; ok {
+			if _, model := p.models[definitionKey(val.RawRefName)]; !model {
+				// declared by a @Definition block
+				continue
+			}
+		}
+
 		def := &Schema{}
 		if _, ok := parser.Types[val.RawRefName]; !ok {
 			logrus.Errorf("Could not find %s package", val.RawRefName)
@@ -121,308 +266,354 @@ func (p *Parser) parseDefinitionModels() {
 		}
 
 		pType := parser.Types[val.RawRefName]
+		keyName := p.registerModel(val.RawRefName)
 
 		if pType.Doc != nil {
 			p.parseDefinitionOptions(def, pType.Doc.List)
+			p.setModelName(keyName, p.parsePropertiesName(pType.Doc.List))
 		}
-		p.parseDefinitionModel(def, pType)
-
-		keyName := val.RawRefName
-		p.swagger.Definitions[fixPath(keyName)] = def
+		p.swagger.Definitions[keyName] = def
+		p.parseModel(keyName, def, pType)
 	}
 }
 
 func (p *Parser) readZone(comments []*ast.Comment) {
-	if len(comments) == 0 {
+	if len(comments) == 0 || p.readSwaggo(comments) {
 		return
 	}
 
 	for i := 0; i < len(comments); i++ {
-		index := findAt(comments[i].Text)
-
-		if index > 0 {
-			tag := comments[i].Text[index:]
-			header := strings.Split(tag, " ")
-
-			switch header[0] {
-			case "@Swagger":
-				i += p.parseSwagger(comments[i:])
-			case "@GlobalParam":
-				p.parseParamGlobal(comments[i : i+1][0])
-			case "@SecurityDefinition":
-				i += p.parseSecurityDefinition(comments[i:])
-			case "@GlobalResponse":
-				p.parseGlobalResponse(comments[i : i+1][0])
-			case "@Definition":
-				p.parseDefinition(comments[i:])
-			case "@Path":
-				i += p.parsePath(comments[i:])
-			}
+		ann, last := p.annotationAt(comments, i)
+		if ann == nil {
+			continue
+		}
+
+		switch ann.tag {
+		case "@Swagger":
+			i += p.parseSwagger(comments[i:])
+		case "@GlobalParam":
+			p.parseParamGlobal(ann)
+			i = last
+		case "@SecurityDefinition":
+			i += p.parseSecurityDefinition(comments[i:])
+		case "@GlobalResponse":
+			p.parseGlobalResponse(ann)
+			i = last
+		case "@Definition":
+			p.parseDefinition(comments[i:])
+		case "@Path":
+			i += p.parsePath(comments[i:])
+		case "@Mixin":
+			// read by readMixins
+			_, n := p.annotations(comments[i:], true)
+			i += n
 		}
 	}
 }
 
 func (p *Parser) parseSwagger(comments []*ast.Comment) int {
-	i := 0
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return i
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Title":
-				p.swagger.Info.Title = vals
-			case "@Description":
-				p.swagger.Info.Description = joinString(p.swagger.Info.Description, vals)
-			case "@BasePath":
-				p.swagger.BasePath = vals
-			case "@Term":
-				p.swagger.Info.TermsOfService = vals
-			case "@Contact":
-				if p.swagger.Info.Contact == nil {
-					p.swagger.Info.Contact = &Contact{}
-				}
-				data := getValueByKey(vals)
-				p.swagger.Info.Contact.Name = data["name"]
-				p.swagger.Info.Contact.Email = data["email"]
-				p.swagger.Info.Contact.URL = data["url"]
-			case "@License":
-				if p.swagger.Info.License == nil {
-					p.swagger.Info.License = &License{}
-				}
-				data := getValueByKey(vals)
-				p.swagger.Info.License.Name = data["name"]
-				p.swagger.Info.License.URL = data["url"]
-			case "@Version":
-				p.swagger.Info.Version = vals
-			case "@Schemes":
-				p.swagger.Schemes = getValueStrings(vals)
-			case "@Consumes":
-				valsArray := getValueStrings(vals)
-				for i := 0; i < len(valsArray); i++ {
-					valsArray[i] = getMime(valsArray[i])
-				}
-				p.swagger.Consumes = valsArray
-			case "@Produces":
-				valsArray := getValueStrings(vals)
-				for i := 0; i < len(valsArray); i++ {
-					valsArray[i] = getMime(valsArray[i])
-				}
-				p.swagger.Produces = valsArray
-			case "@Security":
-				p.swagger.Security = append(p.swagger.Security, getValueMapStrings(vals))
+	anns, n := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Title":
+			p.swagger.Info.Title = vals
+		case "@Description":
+			p.swagger.Info.Description = p.description(p.swagger.Info.Description, ann)
+		case "@BasePath":
+			p.swagger.BasePath = vals
+		case "@Host":
+			p.swagger.Host = vals
+		case "@Term":
+			p.swagger.Info.TermsOfService = vals
+		case "@Contact":
+			if p.swagger.Info.Contact == nil {
+				p.swagger.Info.Contact = &Contact{}
+			}
+			data := ann.values(0)
+			p.swagger.Info.Contact.Name = data["name"]
+			p.swagger.Info.Contact.Email = data["email"]
+			p.swagger.Info.Contact.URL = data["url"]
+		case "@License":
+			if p.swagger.Info.License == nil {
+				p.swagger.Info.License = &License{}
+			}
+			data := ann.values(0)
+			p.swagger.Info.License.Name = data["name"]
+			p.swagger.Info.License.URL = data["url"]
+		case "@Version":
+			p.swagger.Info.Version = vals
+		case "@Schemes":
+			p.swagger.Schemes = getValueStrings(vals)
+		case "@Consumes":
+			valsArray := getValueStrings(vals)
+			for i := 0; i < len(valsArray); i++ {
+				valsArray[i] = getMime(valsArray[i])
 			}
+			p.swagger.Consumes = valsArray
+		case "@Produces":
+			valsArray := getValueStrings(vals)
+			for i := 0; i < len(valsArray); i++ {
+				valsArray[i] = getMime(valsArray[i])
+			}
+			p.swagger.Produces = valsArray
+		case "@Security":
+			p.swagger.Security = append(p.swagger.Security, requirement(ann.args()))
+		case "@Tag":
+			p.parseTag(ann)
+		case "@TypeMap":
+			p.parseTypeMap(ann)
+		case "@ExternalDocs":
+			p.swagger.ExternalDocs = getExternalDocs(ann.values(0))
 		}
 	}
 
-	return i
+	return n
 }
 
-func (p *Parser) parseGlobalResponse(comment *ast.Comment) {
-	index := findAt(comment.Text)
-	if index > 0 {
-		commentText := strings.Replace(comment.Text[index:], "\t", " ", -1)
-		data := strings.SplitN(commentText, " ", 3)
-		if len(data) != 3 {
-			panic("Invalid @GlobalResponse arguments")
+// description returns current with the text of a @Description annotation
+// added as a new line. With file=path the text is read from a file relative
+// to the source file.
+func (p *Parser) description(current string, ann *annotation) string {
+	text := ann.text
+	if strings.HasPrefix(text, "file=") {
+		if args, err := lexArgs(text); err == nil && len(args) == 1 && args[0].key == "file" {
+			text = p.readDescription(ann, args[0])
 		}
+	}
 
-		tag, respName, vals := strings.TrimSpace(data[0]), strings.TrimSpace(data[1]), strings.TrimSpace(data[2])
+	return joinLines(current, text)
+}
 
-		if tag == "@GlobalResponse" {
-			resp := &Responses{}
-			p.parseResponse(resp, getValueByKey(vals))
-			p.swagger.Responses[respName] = resp
+func (p *Parser) readDescription(ann *annotation, file *arg) string {
+	filename := file.value
+	if p.fset != nil && !filepath.IsAbs(filename) {
+		if source := p.fset.Position(ann.pos).Filename; source != "" {
+			filename = filepath.Join(filepath.Dir(source), filename)
 		}
 	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(ann.errorAt(file.offset, fmt.Sprintf("cannot read the description: %s", err)))
+	}
+
+	return strings.TrimRight(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
 }
 
-func (p *Parser) parseParamGlobal(comment *ast.Comment) {
-	index := findAt(comment.Text)
-	if index > 0 {
-		commentText := strings.Replace(comment.Text[index:], "\t", " ", -1)
-		data := strings.SplitN(commentText, " ", 3)
-		if len(data) != 3 {
-			panic("Invalid @GlobalParam arguments")
+func (p *Parser) parseTag(ann *annotation) {
+	name := ann.word(0)
+	if name == "" {
+		panic(ann.errorAt(0, "expected a tag name"))
+	}
+
+	var tag *Tag
+	for _, t := range p.swagger.Tags {
+		if t.Name == name {
+			tag = t
+			break
 		}
+	}
 
-		tag, paramName, vals := strings.TrimSpace(data[0]), strings.TrimSpace(data[1]), strings.TrimSpace(data[2])
+	if tag == nil {
+		tag = &Tag{Name: name}
+		p.swagger.Tags = append(p.swagger.Tags, tag)
+	}
 
-		if tag == "@GlobalParam" {
-			param := &Parameter{}
-			p.parseParam(param, getValueByKey(vals))
-			p.swagger.Parameters[paramName] = param
+	data := ann.values(1)
+	for key, val := range data {
+		switch key {
+		case "description", "desc":
+			tag.Description = val
+		case "docs":
+			if tag.ExternalDocs == nil {
+				tag.ExternalDocs = &ExternalDocs{}
+			}
+			tag.ExternalDocs.URL = val
+		case "docsDesc":
+			if tag.ExternalDocs == nil {
+				tag.ExternalDocs = &ExternalDocs{}
+			}
+			tag.ExternalDocs.Description = val
 		}
 	}
 }
 
+func (p *Parser) parseGlobalResponse(ann *annotation) {
+	if len(ann.args()) < 2 {
+		panic(ann.errorAt(0, "expected a name and the response"))
+	}
+
+	resp := &Responses{}
+	p.parseResponse(resp, responseValues(ann, 1), ann)
+	p.swagger.Responses[ann.word(0)] = resp
+}
+
+func (p *Parser) parseParamGlobal(ann *annotation) {
+	if len(ann.args()) < 2 {
+		panic(ann.errorAt(0, "expected a name and the parameter"))
+	}
+
+	param := &Parameter{}
+	p.parseParam(param, paramValues(ann, 1), ann)
+	p.swagger.Parameters[ann.word(0)] = param
+}
+
 func (p *Parser) parseSecurityDefinition(comments []*ast.Comment) int {
-	i := 0
 	var def *SecurityDefinitions
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return i
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@SecurityDefinition":
-				def = &SecurityDefinitions{}
-				p.swagger.SecurityDefinitions[vals] = def
-			case "@Name":
-				def.Name = vals
-			case "@Type":
-				def.Type = vals
-			case "@Description":
-				def.Description = joinString(def.Description, vals)
-			case "@In":
-				def.In = vals
-			case "@Flow":
-				def.Flow = vals
-			case "@AuthorizationUrl":
-				def.AuthorizationUrl = vals
-			case "@TokenUrl":
-				def.TokenUrl = vals
-			case "@Scopes":
-				def.Scopes = getValueByKey(vals)
-			}
+	anns, n := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@SecurityDefinition":
+			def = &SecurityDefinitions{}
+			p.swagger.SecurityDefinitions[vals] = def
+		case "@Name":
+			def.Name = vals
+		case "@Type":
+			def.Type = vals
+		case "@Description":
+			def.Description = p.description(def.Description, ann)
+		case "@In":
+			def.In = vals
+		case "@Flow":
+			def.Flow = vals
+		case "@AuthorizationUrl":
+			def.AuthorizationUrl = vals
+		case "@TokenUrl":
+			def.TokenUrl = vals
+		case "@Scopes":
+			def.Scopes = ann.values(0)
 		}
 	}
 
-	return i
+	return n
 }
 
 func (p *Parser) parsePath(comments []*ast.Comment) int {
-	i := 0
 	var method *Operation
 	path := ""
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return i
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Path":
-				path = vals
-				if p.swagger.Paths[vals] == nil {
-					p.swagger.Paths[vals] = &Path{}
-				}
-			case "@Method":
-				switch vals {
-				case "GET":
-					method = &Operation{}
-					p.swagger.Paths[path].GET = method
-				case "POST":
-					method = &Operation{}
-					p.swagger.Paths[path].POST = method
-				case "PUT":
-					method = &Operation{}
-					p.swagger.Paths[path].PUT = method
-				case "DELETE":
-					method = &Operation{}
-					p.swagger.Paths[path].DELETE = method
-				case "OPTIONS":
-					method = &Operation{}
-					p.swagger.Paths[path].OPTIONS = method
-				case "HEAD":
-					method = &Operation{}
-					p.swagger.Paths[path].HEAD = method
-				default:
-					panic("Unsupported method")
-				}
-			case "@Consumes":
-				valsArray := getValueStrings(vals)
-				for i := 0; i < len(valsArray); i++ {
-					valsArray[i] = getMime(valsArray[i])
-				}
-				method.Consumes = valsArray
-			case "@Produces":
-				valsArray := getValueStrings(vals)
-				for i := 0; i < len(valsArray); i++ {
-					valsArray[i] = getMime(valsArray[i])
-				}
-				method.Produces = valsArray
-			case "@Summary":
-				method.Summary = vals
-			case "@Description":
-				method.Description = joinString(method.Description, vals)
-			case "@Deprecated":
-				method.Deprecated = true
-			case "@Schemes":
-				method.Schemes = getValueStrings(vals)
-			case "@OperationId":
-				method.OperationId = vals
-			case "@Security":
-				if !strings.Contains(vals, ":") {
-					method.Security = append(method.Security, map[string][]string{vals: []string{}})
-				} else {
-					method.Security = append(method.Security, getValueMapStrings(vals))
-				}
-			case "@Tags":
-				method.Tags = getValueStrings(vals)
-			case "@Param":
-				if method.Parameters == nil {
-					method.Parameters = []*Parameter{}
-				}
-				param := &Parameter{}
-				valArray := getValueByKey(vals)
-				p.parseParam(param, valArray)
-				method.Parameters = append(method.Parameters, param)
-			case "@Response":
-				if method.Responses == nil {
-					method.Responses = make(map[string]*Responses)
-				}
+	anns, n := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Path":
+			path = vals
+			if p.swagger.Paths[vals] == nil {
+				p.swagger.Paths[vals] = &Path{}
+			}
+		case "@Method":
+			method = &Operation{}
+			if !setOperation(p.swagger.Paths[path], vals, method) {
+				panic(ann.errorAt(0, fmt.Sprintf("unsupported method %s", vals)))
+			}
+		default:
+			p.parseOperation(method, ann)
+		}
+	}
 
-				data := strings.SplitN(vals, " ", 2)
-				if len(data) < 1 {
-					panic("Invalid @Response arguments")
-				}
+	return n
+}
 
-				vals = ""
-				code := data[0]
-				if len(data) == 2 {
-					vals = data[1]
-				}
+// parseOperation reads an operation-level annotation of a @Path block or a
+// mixin into method.
+func (p *Parser) parseOperation(method *Operation, ann *annotation) {
+	if !operationTags[ann.tag] {
+		return
+	}
+	if method == nil {
+		panic(ann.errorAt(0, "expected a @Method first"))
+	}
 
-				resp := &Responses{}
-				valArray := getValueByKey(vals)
-				p.parseResponse(resp, valArray)
-				method.Responses[code] = resp
+	vals := ann.text
+	switch ann.tag {
+	case "@Consumes":
+		valsArray := getValueStrings(vals)
+		for i := 0; i < len(valsArray); i++ {
+			valsArray[i] = getMime(valsArray[i])
+		}
+		method.Consumes = valsArray
+	case "@Produces":
+		valsArray := getValueStrings(vals)
+		for i := 0; i < len(valsArray); i++ {
+			valsArray[i] = getMime(valsArray[i])
+		}
+		method.Produces = valsArray
+	case "@Summary":
+		method.Summary = vals
+	case "@Description":
+		// the description of the operation replaces the ones of its
+		// mixins, wherever it is written
+		switch {
+		case p.inMixin > 0 && method.Description != "" && !p.mixinDocs[method]:
+			return
+		case p.inMixin > 0:
+			if p.mixinDocs == nil {
+				p.mixinDocs = make(map[*Operation]bool)
 			}
+			p.mixinDocs[method] = true
+		case p.mixinDocs[method]:
+			method.Description = ""
+			p.mixinDocs[method] = false
+		}
+		method.Description = p.description(method.Description, ann)
+	case "@Deprecated":
+		method.Deprecated = true
+	case "@Schemes":
+		method.Schemes = getValueStrings(vals)
+	case "@OperationId":
+		method.OperationId = vals
+	case "@Security":
+		if req := requirement(ann.args()); !hasRequirement(method.Security, req) {
+			method.Security = append(method.Security, req)
+		}
+	case "@Tags":
+		method.Tags = getValueStrings(vals)
+	case "@ExternalDocs":
+		method.ExternalDocs = getExternalDocs(ann.values(0))
+	case "@Param":
+		param := &Parameter{}
+		valArray := paramValues(ann, 0)
+		p.parseParam(param, valArray, ann)
+		method.Parameters = setParameter(method.Parameters, param)
+	case "@Example":
+		p.parseResponseExample(method, ann)
+	case "@Response":
+		if method.Responses == nil {
+			method.Responses = make(map[string]*Responses)
+		}
+
+		code := ann.word(0)
+		if code == "" {
+			panic(ann.errorAt(0, "missing response code"))
 		}
-	}
 
-	return i
+		resp := &Responses{}
+		if method.Responses[code] != nil {
+			// keep examples declared before the response
+			resp.Examples = method.Responses[code].Examples
+		}
+		valArray := responseValues(ann, 1)
+		p.parseResponse(resp, valArray, ann)
+		method.Responses[code] = resp
+	case "@Use":
+		p.useMixins(method, ann, nil)
+	}
 }
 
 func (p *Parser) parseDefinition(comments []*ast.Comment) int {
-	i := 0
 	var defName string
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return i
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Definition":
-				defName = vals
-				p.parseNamedDefinition(comments, defName)
-			}
+	anns, n := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Definition":
+			defName = vals
+			p.parseNamedDefinition(comments, defName)
 		}
 	}
 
-	return i
+	return n
 }
 
 func (p *Parser) parseNamedDefinition(comments []*ast.Comment, defName string) int {
@@ -432,93 +623,101 @@ func (p *Parser) parseNamedDefinition(comments []*ast.Comment, defName string) i
 		// already parsed this somewhere else, bye
 		i := 0
 		for ; i < len(comments); i++ {
-			if strings.TrimSpace(comments[i].Text) == "//" {
+			if isTerminator(comments[i]) {
 				return i
 			}
 		}
 		return i
 	}
-	i := 0
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return i
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Description":
-				p.swagger.Definitions[defName].Description = joinString(p.swagger.Definitions[defName].Description, vals)
-			case "@Property":
-				if p.swagger.Definitions[defName].Properties == nil {
-					p.swagger.Definitions[defName].Properties = make(map[string]*Schema)
-				}
 
-				propText := strings.Replace(vals, "\t", " ", -1)
-				data := strings.SplitN(propText, " ", 2)
-				if len(data) != 2 {
-					panic("Invalid @Property arguments")
-				}
+	anns, n := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Description":
+			p.swagger.Definitions[defName].Description = p.description(p.swagger.Definitions[defName].Description, ann)
+		case "@Property":
+			if p.swagger.Definitions[defName].Properties == nil {
+				p.swagger.Definitions[defName].Properties = make(map[string]*Schema)
+			}
 
-				propName, propVals := strings.TrimSpace(data[0]), strings.TrimSpace(data[1])
-				def := &Schema{}
-				valArray := getValueByKey(propVals)
-				p.parseDefinitionField(def, valArray)
-				p.swagger.Definitions[defName].Properties[propName] = def
-			case "@Type":
-				p.swagger.Definitions[defName].Type, p.swagger.Definitions[defName].Format, _ = getTypeFormat(vals)
-			case "@Required":
-				p.swagger.Definitions[defName].Required = getValueStrings(vals)
-			case "@Enum":
-				data := getValueStrings(vals)
-				if p.swagger.Definitions[defName].Enum == nil {
-					p.swagger.Definitions[defName].Enum = make([]string, 0)
-					for _, val := range data {
-						p.swagger.Definitions[defName].Enum = append(p.swagger.Definitions[defName].Enum, val)
-					}
-				}
-			case "@Items":
-				if p.swagger.Definitions[defName].Items == nil {
-					p.swagger.Definitions[defName].Items = &Schema{}
-				}
-				data := getValueByKey(vals)
-				for key, val := range data {
-					p.parseSchema(p.swagger.Definitions[defName].Items, key, val)
+			if len(ann.args()) < 2 {
+				panic(ann.errorAt(0, "expected a name and the property"))
+			}
+
+			checkNumbers(ann)
+			def := &Schema{}
+			valArray := ann.values(1)
+			p.parseDefinitionField(def, valArray)
+			p.swagger.Definitions[defName].Properties[ann.word(0)] = def
+		case "@Type":
+			setTypeFormat(p.swagger.Definitions[defName], vals)
+		case "@Format":
+			p.swagger.Definitions[defName].Format = vals
+		case "@ExternalDocs":
+			p.swagger.Definitions[defName].ExternalDocs = getExternalDocs(ann.values(0))
+		case "@Example":
+			p.swagger.Definitions[defName].Example = rawExample(vals)
+		case "@Required":
+			p.swagger.Definitions[defName].Required = getValueStrings(vals)
+		case "@Enum":
+			data := getValueStrings(vals)
+			if p.swagger.Definitions[defName].Enum == nil {
+				p.swagger.Definitions[defName].Enum = make([]string, 0)
+				for _, val := range data {
+					p.swagger.Definitions[defName].Enum = append(p.swagger.Definitions[defName].Enum, val)
 				}
 			}
+		case "@Items":
+			if p.swagger.Definitions[defName].Items == nil {
+				p.swagger.Definitions[defName].Items = &Schema{}
+			}
+			data := ann.values(0)
+			for key, val := range data {
+				p.parseSchema(p.swagger.Definitions[defName].Items, key, val)
+			}
 		}
 	}
 
-	return i
+	return n
 }
 
 func (p *Parser) parseDefinitionField(def *Schema, vals map[string]string) {
 	for key, val := range vals {
 		switch {
 		case key == "$ref":
-			def.Ref = "#/definitions/" + fixPath(val)
+			def.Ref = "#/definitions/" + definitionKey(val)
 		case key == "type":
-			def.Type, def.Format, _ = getTypeFormat(val)
+			setTypeFormat(def, val)
+		case key == "format":
+			def.Format = val
 		case key == "description" || key == "desc":
 			def.Description = val
+		case key == "enum":
+			def.Enum = getValueStrings(val)
+		case key == "example":
+			def.Example = rawExample(val)
+		case key == "nullable":
+			def.Nullable = true
 		case pathMatch("items.*", key):
 			if def.Items == nil {
 				def.Items = &Schema{}
 			}
 			p.parseSchema(def.Items, strings.TrimPrefix(key, "items."), val)
+		default:
+			parseSchemaConstraint(def, key, val)
 		}
 	}
 }
 
-func (p *Parser) parseParam(param *Parameter, vals map[string]string) {
+func (p *Parser) parseParam(param *Parameter, vals map[string]string, ann *annotation) {
 	for key, val := range vals {
 		switch {
 		case key == "name":
 			param.Name = val
 		case key == "$ref":
 			param.Ref = "#/parameters/" + val
-			p.usedParameters = append(p.usedParameters, val)
+			p.usedParameters = append(p.usedParameters, &reference{name: val, ann: ann})
 		case key == "in":
 			param.In = val
 		case key == "description" || key == "desc":
@@ -531,7 +730,13 @@ func (p *Parser) parseParam(param *Parameter, vals map[string]string) {
 			}
 			p.parseSchema(param.Schema, strings.TrimPrefix(key, "schema."), val)
 		case key == "type":
-			param.Type, param.Format, _ = getTypeFormat(val)
+			typ, format, _ := getTypeFormat(val)
+			param.Type = typ
+			if param.Format == "" {
+				param.Format = format
+			}
+		case key == "format":
+			param.Format = val
 		case key == "allowEmptyValue":
 			param.AllowEmptyValue = true
 		case pathMatch("items.*", key):
@@ -539,6 +744,8 @@ func (p *Parser) parseParam(param *Parameter, vals map[string]string) {
 				param.Items = &Items{}
 			}
 			p.parseItem(param.Items, strings.TrimPrefix(key, "items."), val)
+		case key == "collectionFormat":
+			param.CollectionFormat = val
 		case key == "default":
 			param.Default = val
 		case key == "maximum":
@@ -561,14 +768,24 @@ func (p *Parser) parseParam(param *Parameter, vals map[string]string) {
 			param.Enum = valsArray
 		}
 	}
+
+	if val, ok := vals["example"]; ok {
+		if param.Schema != nil {
+			param.Schema.Example = parseExample(val)
+		} else {
+			param.Example = typedExample(param.Type, val)
+		}
+	}
 }
 
 func (p *Parser) parseSchema(s *Schema, key, val string) {
 	switch {
 	case key == "type":
-		s.Type, s.Format, _ = getTypeFormat(val)
+		setTypeFormat(s, val)
+	case key == "format":
+		s.Format = val
 	case key == "$ref":
-		s.Ref = "#/definitions/" + fixPath(val)
+		s.Ref = "#/definitions/" + definitionKey(val)
 		s.RawRefName = val
 		p.usedDefinitions = append(p.usedDefinitions, s)
 	case pathMatch("items.*", key):
@@ -585,7 +802,13 @@ func (p *Parser) parseItem(item *Items, key, val string) {
 	// 	item.Ref = "#/definitions/" + val
 	// 	p.usedDefinitions[val] = struct{}{}
 	case key == "type":
-		item.Type, item.Format, _ = getTypeFormat(val)
+		typ, format, _ := getTypeFormat(val)
+		item.Type = typ
+		if item.Format == "" {
+			item.Format = format
+		}
+	case key == "format":
+		item.Format = val
 	case key == "default":
 		item.Default = val
 	case key == "maximum":
@@ -609,12 +832,12 @@ func (p *Parser) parseItem(item *Items, key, val string) {
 	}
 }
 
-func (p *Parser) parseResponse(resp *Responses, vals map[string]string) {
+func (p *Parser) parseResponse(resp *Responses, vals map[string]string, ann *annotation) {
 	for key, val := range vals {
 		switch {
 		case key == "$ref":
 			resp.Ref = "#/responses/" + val
-			p.usedResponses = append(p.usedResponses, val)
+			p.usedResponses = append(p.usedResponses, &reference{name: val, ann: ann})
 		case key == "description" || key == "desc":
 			resp.Description = val
 		case pathMatch("schema.*", key):
@@ -627,79 +850,93 @@ func (p *Parser) parseResponse(resp *Responses, vals map[string]string) {
 }
 
 func (p *Parser) parseDefinitionOptions(def *Schema, comments []*ast.Comment) {
-	i := 0
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Description":
-				def.Description = joinString(def.Description, vals)
-			}
+	anns, _ := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Description":
+			def.Description = p.description(def.Description, ann)
+		case "@ExternalDocs":
+			def.ExternalDocs = getExternalDocs(ann.values(0))
+		case "@Example":
+			def.Example = rawExample(vals)
 		}
 	}
 }
 
 func (p *Parser) parsePropertiesName(comments []*ast.Comment) string {
-	i := 0
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return ""
-		}
-
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Name":
-				return vals
-			}
+	anns, _ := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Name":
+			return vals
 		}
 	}
 
 	return ""
 }
 
-func (p *Parser) parsePropertiesOptions(name string, def *Schema, prop *Schema, comments []*ast.Comment) {
-	i := 0
-	for ; i < len(comments); i++ {
-		if strings.TrimSpace(comments[i].Text) == "//" {
-			return
+func (p *Parser) parsePropertiesNullable(comments []*ast.Comment) (bool, bool) {
+	anns, _ := p.annotations(comments, true)
+	for _, ann := range anns {
+		switch ann.tag {
+		case "@Nullable":
+			return true, true
+		case "@NotNull":
+			return false, true
 		}
+	}
 
-		index := findAt(comments[i].Text)
-		if index > 0 {
-			tag, vals := getValues(comments[i].Text[index:])
-			switch tag {
-			case "@Description":
-				prop.Description = joinString(prop.Description, vals)
-			case "@Required":
-				def.Required = append(def.Required, name)
-			}
+	return false, false
+}
+
+func (p *Parser) parsePropertiesOptions(name string, def *Schema, prop *Schema, comments []*ast.Comment) {
+	anns, _ := p.annotations(comments, true)
+	for _, ann := range anns {
+		tag, vals := ann.tag, ann.text
+		switch tag {
+		case "@Description":
+			prop.Description = p.description(prop.Description, ann)
+		case "@Required":
+			appendRequired(def, name)
+		case "@Example":
+			prop.Example = rawExample(vals)
 		}
 	}
 }
 func (p *Parser) parseDefinitionModel(def *Schema, pType *parsetype.Type) {
+	if mapped := p.mappedType(walkerTypeName(pType)); mapped != nil {
+		applyMappedType(def, mapped)
+		return
+	}
+
 	switch pType.Type {
 	case "ref":
-		if pType.RefType != nil {
+		if pType.RefType != nil && strings.Contains(pType.RefType.Name, "[") {
+			def.Ref = "#/definitions/" + p.instantiate(pType.RefType.Name)
+		} else if pType.RefType != nil {
 			switch pType.RefType.Type {
 			case "struct", "ref":
 				// p.parseDefinitionModel(def, pType.RefType)
-				pType.RefType.Name = fixPath(pType.RefType.Name)
-				def.Ref = "#/definitions/" + fixPath(pType.RefType.Name)
+				keyName := p.registerModel(pType.RefType.Name)
+				if pType.RefType.Doc != nil {
+					p.setModelName(keyName, p.parsePropertiesName(pType.RefType.Doc.List))
+				}
+				pType.RefType.Name = keyName
+				def.Ref = "#/definitions/" + keyName
+				if p.cutCycle(keyName, pType.RefType) {
+					break
+				}
 				if _, ok := p.swagger.Definitions[pType.RefType.Name]; !ok {
 					p.swagger.Definitions[pType.RefType.Name] = &Schema{}
-					p.parseDefinitionModel(p.swagger.Definitions[pType.RefType.Name], pType.RefType)
+					p.parseModel(keyName, p.swagger.Definitions[pType.RefType.Name], pType.RefType)
 				}
 			default:
 				// A primitive or an alias for a primitive.
 				// override with docs if found
 				if pType.RefType.Doc != nil {
+					p.registerModel(pType.RefType.Name)
 					p.parseNamedDefinition(pType.RefType.Doc.List, fixPath(pType.RefType.Name))
 					newDef := p.swagger.Definitions[fixPath(pType.RefType.Name)]
 					// def will already be populated with arlong fields if present before this
@@ -723,81 +960,58 @@ func (p *Parser) parseDefinitionModel(def *Schema, pType *parsetype.Type) {
 			}
 		}
 	case "struct":
-		def.Properties = make(map[string]*Schema)
-		for key, val := range pType.Properties {
-			propDef := &Schema{}
-
-			name := ""
-			nameDoc := ""
-
-			if val.Doc != nil {
-				nameDoc = p.parsePropertiesName(val.Doc.List)
-			}
-
-			if val.Tags != "" && val.Tags.Get(`json`) != "" {
-				jsonData := strings.Split(val.Tags.Get(`json`), ",")
-				if jsonData[0] == "-" || jsonData[0] == "" {
-					continue
-				}
-				name = jsonData[0]
-			} else if nameDoc != "" {
-				name = nameDoc
-			} else {
-				name = key
-			}
-
-			if arlongTags := val.Tags.Get(`arlong`); arlongTags != "" {
-				for _, tag := range strings.Split(arlongTags, ",") {
-					tag = strings.TrimSpace(tag)
-					data := strings.Split(tag, "=")
-					switch {
-					case data[0] == "required":
-						def.Required = append(def.Required, name)
-					case data[0] == "type":
-						propDef.Type, propDef.Format, _ = getTypeFormat(data[1])
-					case data[0] == "description" || data[0] == "desc":
-						propDef.Description = joinString(propDef.Description, data[1])
-					case data[0] == "enum":
-						valsArray := getValueStrings(data[1])
-						for i := 0; i < len(valsArray); i++ {
-							valsArray[i] = getMime(valsArray[i])
-						}
-						propDef.Enum = valsArray
-					}
-				}
-			}
-
-			if val.Doc != nil {
-				p.parsePropertiesOptions(name, def, propDef, val.Doc.List)
-			}
-
-			def.Properties[name] = propDef
-			p.parseDefinitionModel(propDef, val)
+		frame, ok := p.enterStruct(def, pType)
+		if !ok {
+			return
+		}
+		if frame != nil {
+			defer p.leaveStruct(frame)
 		}
+
+		def.Properties = make(map[string]*Schema)
+		promote(def, p.modelFields(pType, 0, map[string]bool{}))
+		sort.Strings(def.Required)
 	case "map":
 		def.Type = "object"
 		def.AdditionalProperties = &Schema{}
 
 		p.parseDefinitionModel(def.AdditionalProperties, pType.MapType)
 	case "array":
+		if pType.ArrayType != nil && (pType.ArrayType.Type == "byte" || pType.ArrayType.Type == "uint8") {
+			// encoding/json sends []byte as base64
+			def.Type, def.Format = "string", "byte"
+			return
+		}
+
 		def.Type = "array"
 		def.Items = &Schema{}
 		p.parseDefinitionModel(def.Items, pType.ArrayType)
 	default:
+		if _, _, ok := splitGeneric(pType.Type); ok {
+			def.Ref = "#/definitions/" + p.instantiate(pType.Type)
+			return
+		}
 		def.Type, def.Format, _ = getTypeFormat(pType.Type)
 	}
 }
 
+// reference is a $ref to a global parameter or response and the annotation
+// holding it.
+type reference struct {
+	name string
+	ann  *annotation
+}
+
 func (p *Parser) validate() {
-	for _, defName := range p.usedParameters {
-		if _, ok := p.swagger.Parameters[defName]; !ok {
-			panic("cannot found " + defName + " in parameters")
+	for _, ref := range p.usedParameters {
+		if _, ok := p.swagger.Parameters[ref.name]; !ok {
+			panic(ref.ann.errorAt(ref.ann.offset("$ref"), fmt.Sprintf("unknown global parameter %s", ref.name)))
 		}
 	}
 
-	for _, defName := range p.usedResponses {
-		if _, ok := p.swagger.Responses[defName]; !ok {
-			panic("cannot found " + defName + " in responses")
+	for _, ref := range p.usedResponses {
+		if _, ok := p.swagger.Responses[ref.name]; !ok {
+			panic(ref.ann.errorAt(ref.ann.offset("$ref"), fmt.Sprintf("unknown global response %s", ref.name)))
 		}
 	}
 }
@@ -1,7 +1,17 @@
 package spec
 
 import (
-	"github.com/kr/pretty"
+	"encoding/json"
+	"fmt"
+	. "github.com/peak6/arlong/schema"
+	"github.com/peak6/utils/parsetype"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -34,6 +44,9 @@ type Hello9 struct {
 // @Consumes json xml
 // @Produces json xml
 // @Security petstore_auth=write:pets,read:pets
+// @ExternalDocs url=http://www.plimble.com desc="Find out more"
+// @Tag attempts desc="Attempt operations" docs=http://www.plimble.com/attempts
+// @Tag a desc="Tag a"
 //
 // @SecurityDefinition petstore_auth
 // @Type oauth2
@@ -74,9 +87,1239 @@ type Hello9 struct {
 // @Security petstore_auth=write:pets,read:pets
 // @Response 200 desc=123123 schema.$ref=arlong.Hello9
 func TestAnnotation(t *testing.T) {
-	basePath := "/Users/witooh/dev/go/src/github.com/plimble/arlong"
-	parser := NewParser(basePath)
-	b, _ := parser.JSON()
-	pretty.Println(string(b))
-	pretty.Println(swagger.Definitions)
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "parser_test.go", nil, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.fset = fset
+	for _, group := range file.Comments {
+		parser.readZone(group.List)
+	}
+	swagger := parser.swagger
+
+	if swagger.Info.Title != "Api" || swagger.Info.Version != "1.1.1" || swagger.Info.Description != "Super api" {
+		t.Errorf("unexpected info %#v", swagger.Info)
+	}
+	if len(swagger.Tags) != 2 || swagger.Tags[0].Name != "attempts" || swagger.Tags[1].Description != "Tag a" {
+		t.Errorf("unexpected tags %#v", swagger.Tags)
+	}
+	if def := swagger.SecurityDefinitions["petstore_auth"]; def == nil || def.Type != "oauth2" || def.Scopes["write:pets"] != "modify pets in your account" {
+		t.Errorf("unexpected security definition %#v", def)
+	}
+	if param := swagger.Parameters["userParam"]; param == nil || param.In != "body" || !param.Required || param.Schema.RawRefName != "arlong.Hello9" {
+		t.Errorf("unexpected global parameter %#v", param)
+	}
+	if resp := swagger.Responses["notFound"]; resp == nil || resp.Description != "Entity not found." {
+		t.Errorf("unexpected global response %#v", resp)
+	}
+
+	attempts := swagger.Paths["/attempts"].GET
+	if attempts == nil || attempts.OperationId != "GetAttempts" || len(attempts.Parameters) != 5 || attempts.Responses["200"].Schema.Type != "array" {
+		t.Fatalf("unexpected /attempts operation %#v", attempts)
+	}
+	start := swagger.Paths["/user/jack/{id}"].GET
+	if start == nil || !start.Deprecated || start.Summary != "this is summary" || len(start.Tags) != 3 || len(start.Security) != 1 {
+		t.Fatalf("unexpected /user/jack/{id} operation %#v", start)
+	}
+
+	// the global parameters used by /attempts are not declared
+	func() {
+		defer func() {
+			if err, ok := recover().(*SyntaxError); !ok || !strings.HasPrefix(err.Error(), "parser_test.go:") || !strings.Contains(err.Error(), "unknown global parameter limitQuery") {
+				t.Errorf("expected an unknown parameter error, got %v", err)
+			}
+		}()
+		parser.validate()
+	}()
+
+	if _, err := NewParser(filepath.Join(t.TempDir(), "missing")).JSON(); err == nil {
+		t.Error("expected an error for a missing base path")
+	}
+}
+
+// testAnnotation lexes an annotation written on a single comment line.
+func testAnnotation(tag, text string) *annotation {
+	return newAnnotation(nil, &ast.Comment{Text: "// " + tag + " " + text})
+}
+
+func TestParseTag(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.parseTag(testAnnotation("@Tag", `user desc="Operations about user" docs=http://docs.company.com/user`))
+	parser.parseTag(testAnnotation("@Tag", `store desc="Store"`))
+	parser.parseTag(testAnnotation("@Tag", `user desc="Users"`))
+
+	if len(parser.swagger.Tags) != 2 {
+		t.Fatalf("expected 2 tags, got %d", len(parser.swagger.Tags))
+	}
+	if parser.swagger.Tags[0].Name != "user" || parser.swagger.Tags[1].Name != "store" {
+		t.Errorf("tags not in declaration order: %s, %s", parser.swagger.Tags[0].Name, parser.swagger.Tags[1].Name)
+	}
+	if parser.swagger.Tags[0].Description != "Users" {
+		t.Errorf("unexpected description %q", parser.swagger.Tags[0].Description)
+	}
+	if parser.swagger.Tags[0].ExternalDocs == nil || parser.swagger.Tags[0].ExternalDocs.URL != "http://docs.company.com/user" {
+		t.Errorf("unexpected external docs %v", parser.swagger.Tags[0].ExternalDocs)
+	}
+}
+
+func TestResolveDefinitionNames(t *testing.T) {
+	parser := NewParser("")
+	parser.Naming = NamingShort
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+
+	for _, raw := range []string{"github.com/org/svc/models.User", "github.com/org/other/models.User", "github.com/org/svc/models.Group"} {
+		key := parser.registerModel(raw)
+		parser.swagger.Definitions[key] = &Schema{}
+	}
+	parser.swagger.Definitions["Group"] = &Schema{}
+	parser.swagger.Definitions["github.com.org.svc.models.Group"].Properties = map[string]*Schema{
+		"owner": {Ref: "#/definitions/github.com.org.other.models.User"},
+	}
+
+	account := parser.registerModel("github.com/org/svc/models.Account")
+	parser.swagger.Definitions[account] = &Schema{}
+	parser.setModelName(account, "Group")
+
+	if err := parser.resolveDefinitionNames(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"svc.models.User", "other.models.User", "models.Group", "Group", "Account"} {
+		if _, ok := parser.swagger.Definitions[name]; !ok {
+			t.Errorf("missing definition %s in %v", name, parser.swagger.Definitions)
+		}
+	}
+
+	ref := parser.swagger.Definitions["models.Group"].Properties["owner"].Ref
+	if ref != "#/definitions/other.models.User" {
+		t.Errorf("reference was not renamed: %s", ref)
+	}
+
+	// two keys for one type cannot be told apart at any depth
+	parser.swagger = New()
+	parser.models = map[string]*modelName{"a": {raw: "org/models.User"}, "b": {raw: "org/models.User"}}
+	parser.swagger.Definitions["a"] = &Schema{}
+	parser.swagger.Definitions["b"] = &Schema{}
+	err := parser.resolveDefinitionNames()
+	if err == nil || err.Error() != "definition name org.models.User is used by both org/models.User and org/models.User" {
+		t.Errorf("expected an error for an ambiguous name, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.Roots = []string{"Root"}
+	parser.swagger.Paths["/users"] = &Path{GET: &Operation{
+		Parameters: []*Parameter{{Ref: "#/parameters/limit"}},
+		Responses: map[string]*Responses{
+			"200": {Schema: &Schema{Type: "array", Items: &Schema{Ref: "#/definitions/User"}}},
+			"404": {Ref: "#/responses/notFound"},
+		},
+		Security: []map[string][]string{{"oauth": {}}},
+	}}
+	parser.swagger.Definitions["User"] = &Schema{Properties: map[string]*Schema{"group": {Ref: "#/definitions/Group"}}}
+	parser.swagger.Definitions["Group"] = &Schema{}
+	parser.swagger.Definitions["Error"] = &Schema{}
+	parser.swagger.Definitions["Orphan"] = &Schema{}
+	parser.swagger.Definitions["Root"] = &Schema{}
+	parser.swagger.Parameters["limit"] = &Parameter{}
+	parser.swagger.Parameters["skip"] = &Parameter{}
+	parser.swagger.Responses["notFound"] = &Responses{Schema: &Schema{Ref: "#/definitions/Error"}}
+	parser.swagger.Responses["conflict"] = &Responses{}
+	parser.swagger.SecurityDefinitions["oauth"] = &SecurityDefinitions{}
+	parser.swagger.SecurityDefinitions["key"] = &SecurityDefinitions{}
+
+	unused := parser.findUnused()
+	if len(unused.Definitions) != 1 || unused.Definitions[0] != "Orphan" {
+		t.Errorf("unexpected unused definitions %v", unused.Definitions)
+	}
+	if len(unused.Parameters) != 1 || unused.Parameters[0] != "skip" {
+		t.Errorf("unexpected unused parameters %v", unused.Parameters)
+	}
+	if len(unused.Responses) != 1 || unused.Responses[0] != "conflict" {
+		t.Errorf("unexpected unused responses %v", unused.Responses)
+	}
+	if len(unused.SecurityDefinitions) != 1 || unused.SecurityDefinitions[0] != "key" {
+		t.Errorf("unexpected unused security definitions %v", unused.SecurityDefinitions)
+	}
+
+	parser.prune(unused)
+	if _, ok := parser.swagger.Definitions["Orphan"]; ok {
+		t.Error("Orphan was not pruned")
+	}
+	if len(parser.swagger.Definitions) != 4 {
+		t.Errorf("unexpected definitions after prune %v", parser.swagger.Definitions)
+	}
+}
+
+func TestGenericNames(t *testing.T) {
+	base, args, ok := splitGeneric("github.com/org/models.Page[github.com/org/models.Result[github.com/org/models.User], []string]")
+	if !ok || base != "github.com/org/models.Page" || len(args) != 2 {
+		t.Fatalf("unexpected split %s %v", base, args)
+	}
+	if args[0] != "github.com/org/models.Result[github.com/org/models.User]" || args[1] != "[]string" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+
+	if name := instanceRaw("github.com/org/models.Page[github.com/org/models.User]"); name != "github.com/org/models.PageUser" {
+		t.Errorf("unexpected instance name %s", name)
+	}
+	if name := instanceRaw("models.Page[models.Result[models.User],[]string]"); name != "models.PageResultUserStringList" {
+		t.Errorf("unexpected instance name %s", name)
+	}
+	if _, _, ok := splitGeneric("map[string]models.User"); ok {
+		t.Error("map type reported as generic")
+	}
+
+	types := namedTypes("models.Page[map[string]other.User]")
+	if len(types) != 2 || types[0] != "models.Page" || types[1] != "other.User" {
+		t.Errorf("unexpected named types %v", types)
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	src := `package models
+
+type T struct{ Name string }
+
+type Box[T any] struct {
+	Value  T             ` + "`json:\"value\"`" + `
+	List   []T           ` + "`json:\"list\"`" + `
+	ByName map[string]*T ` + "`json:\"byName\"`" + `
+	Next   *Box[T]       ` + "`json:\"next\"`" + `
+	Count  int           ` + "`json:\"count\"`" + `
+}
+`
+	file, err := goparser.ParseFile(token.NewFileSet(), "models.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.generics = make(map[string][]string)
+	parser.sources = map[string][]*ast.File{"github.com/org/models": {file}}
+	parser.types = parsetype.NewParser()
+
+	// the walker resolves the type parameter T to the package's own T
+	realT := &parsetype.Type{Name: "github.com/org/models.T", Type: "struct", Properties: map[string]*parsetype.Type{
+		"Name": {Type: "string"},
+	}}
+	parser.types.Types["github.com/org/models.Box"] = &parsetype.Type{Name: "github.com/org/models.Box", Type: "struct", Properties: map[string]*parsetype.Type{
+		"Value":  {Type: "ref", RefType: realT, Tags: `json:"value"`},
+		"List":   {Type: "array", ArrayType: &parsetype.Type{Type: "ref", RefType: realT}, Tags: `json:"list"`},
+		"ByName": {Type: "map", MapType: &parsetype.Type{Type: "ref", RefType: realT}, Tags: `json:"byName"`},
+		"Next":   {Type: "ref", RefType: &parsetype.Type{Name: "github.com/org/models.Box[T]", Type: "struct"}, Tags: `json:"next"`},
+		"Count":  {Type: "int", Tags: `json:"count"`},
+	}}
+	parser.swagger.Definitions["github.com.org.models.T"] = &Schema{Properties: map[string]*Schema{"Name": {Type: "string"}}}
+
+	key := parser.instantiate("github.com/org/models.Box[string]")
+	def := parser.swagger.Definitions[key]
+	if def == nil || def.Properties["value"].Type != "string" || def.Properties["value"].Ref != "" {
+		t.Fatalf("type parameter was not substituted in %s: %#v", key, def)
+	}
+	if list := def.Properties["list"]; list.Type != "array" || list.Items.Type != "string" {
+		t.Errorf("unexpected list %#v", list)
+	}
+	if byName := def.Properties["byName"]; byName.AdditionalProperties == nil || byName.AdditionalProperties.Type != "string" || !byName.Nullable {
+		t.Errorf("unexpected map %#v", byName)
+	}
+	if next := def.Properties["next"]; next.Ref != "#/definitions/"+key {
+		t.Errorf("expected next to refer to %s, got %#v", key, next)
+	}
+	if count := def.Properties["count"]; count.Type != "integer" {
+		t.Errorf("unexpected count %#v", count)
+	}
+
+	if real := parser.swagger.Definitions["github.com.org.models.T"]; real == nil || real.Properties["Name"] == nil {
+		t.Errorf("the package's own T was dropped or changed: %#v", real)
+	}
+	for name := range parser.swagger.Definitions {
+		if strings.HasPrefix(name, "github.com.org.models.BoxT") {
+			t.Errorf("unexpected instance of the bare type parameter %s", name)
+		}
+	}
+
+	parser.instantiate("github.com/org/models.Box[github.com/a/x.User]")
+	parser.instantiate("github.com/org/models.Box[x.User]")
+	if len(parser.instanceErrs) != 0 {
+		t.Fatalf("unexpected errors %v", parser.instanceErrs)
+	}
+	parser.instantiate("github.com/org/models.Box[github.com/b/y.User]")
+	parser.instantiate("github.com/org/models.Missing[string]")
+	parser.instantiate("github.com/org/models.Box[string,int]")
+	if errs := parser.instanceErrs; len(errs) != 3 ||
+		!strings.Contains(errs[0].Error(), "both instantiated as github.com.org.models.BoxUser") ||
+		!strings.Contains(errs[1].Error(), "could not find github.com/org/models.Missing") ||
+		!strings.Contains(errs[2].Error(), "expects 1 type arguments, got 2") {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestMappedType(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+	parser.parseTypeMap(testAnnotation("@TypeMap", `money.Amount type=string desc="Decimal amount"`))
+
+	cases := map[string]string{
+		"github.com/google/uuid.UUID":           "string/uuid",
+		"github.com.google.uuid.UUID":           "string/uuid",
+		"time.Duration":                         "integer/int64",
+		"github.com/shopspring/decimal.Decimal": "string/decimal",
+		"github.com/org/money.Amount":           "string/",
+		"math/big.Float":                        "string/",
+		"net.IP":                                "string/",
+	}
+	for raw, expected := range cases {
+		def := parser.mappedType(raw)
+		if def == nil {
+			t.Errorf("%s is not mapped", raw)
+			continue
+		}
+		if def.Type+"/"+def.Format != expected {
+			t.Errorf("%s mapped to %s/%s, expected %s", raw, def.Type, def.Format, expected)
+		}
+	}
+
+	for _, raw := range []string{"string", "github.com/org/uuid.UUID", "database/sql.NullString", "net/url.URL"} {
+		if def := parser.mappedType(raw); def != nil {
+			t.Errorf("%s should not be mapped, got %v", raw, def)
+		}
+	}
+}
+
+func TestTypeFormat(t *testing.T) {
+	cases := map[string]string{
+		"uint":      "integer/int64",
+		"uint32":    "integer/int64",
+		"uint16":    "integer/int32",
+		"Time":      "string/date-time",
+		"time":      "string/date-time",
+		"time.Time": "string/date-time",
+	}
+	for val, expected := range cases {
+		if typ, format, _ := getTypeFormat(val); typ+"/"+format != expected {
+			t.Errorf("%s gave %s/%s, expected %s", val, typ, format, expected)
+		}
+	}
+}
+
+func TestJSONTagOptions(t *testing.T) {
+	parser := NewParser("")
+	parser.InferRequired = true
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+
+	base := &parsetype.Type{Name: "Base", Type: "struct", Properties: map[string]*parsetype.Type{
+		"Created": {Type: "string", Tags: `json:"created"`},
+		"Name":    {Type: "string", Tags: `json:"name,omitempty"`},
+	}}
+	model := &parsetype.Type{Name: "Model", Type: "struct", Properties: map[string]*parsetype.Type{
+		"ID":     {Type: "int64", Tags: `json:"id,string"`},
+		"Name":   {Type: "string", Tags: `json:"name"`},
+		"Note":   {Type: "string", Tags: `json:"note,omitempty"`},
+		"Hidden": {Type: "string", Tags: `json:"-"`},
+		"Base":   {Type: "ref", RefType: base},
+	}}
+
+	def := &Schema{}
+	parser.parseDefinitionModel(def, model)
+
+	if def.Properties["id"] == nil || def.Properties["id"].Type != "string" || def.Properties["id"].Format != "int64" {
+		t.Errorf("unexpected id schema %v", def.Properties["id"])
+	}
+	if _, ok := def.Properties["Hidden"]; ok {
+		t.Error("json:\"-\" field was not skipped")
+	}
+	if _, ok := def.Properties["Base"]; ok {
+		t.Error("embedded struct was not flattened")
+	}
+	if _, ok := def.Properties["created"]; !ok {
+		t.Error("embedded field was not promoted")
+	}
+
+	required := strings.Join(def.Required, ",")
+	if required != "created,id,name" {
+		t.Errorf("unexpected required fields %s", required)
+	}
+}
+
+func TestNullable(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+
+	notNull := &ast.CommentGroup{List: []*ast.Comment{{Text: "// @NotNull"}}}
+	model := &parsetype.Type{Name: "Model", Type: "struct", Properties: map[string]*parsetype.Type{
+		"Name":   {Type: "string", Tags: `json:"name"`},
+		"Tags":   {Type: "array", ArrayType: &parsetype.Type{Type: "string"}, Tags: `json:"tags"`},
+		"Labels": {Type: "map", MapType: &parsetype.Type{Type: "string"}, Tags: `json:"labels"`, Doc: notNull},
+	}}
+
+	def := &Schema{}
+	parser.parseDefinitionModel(def, model)
+
+	if def.Properties["name"].Nullable {
+		t.Error("string field should not be nullable")
+	}
+	if !def.Properties["tags"].Nullable {
+		t.Error("slice field should be nullable")
+	}
+	if def.Properties["labels"].Nullable {
+		t.Error("@NotNull did not override map nullability")
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+
+	node := &parsetype.Type{Name: "pkg.Node", Type: "struct"}
+	node.Properties = map[string]*parsetype.Type{
+		"Children": {Type: "array", ArrayType: node, Tags: `json:"children"`},
+	}
+
+	def := &Schema{}
+	parser.swagger.Definitions["pkg.Node"] = def
+	parser.parseModel("pkg.Node", def, node)
+	if ref := def.Properties["children"].Items.Ref; ref != "#/definitions/pkg.Node" {
+		t.Errorf("array of self was not cut with a $ref: %q", ref)
+	}
+
+	comment := &parsetype.Type{Name: "pkg.Comment", Type: "struct"}
+	comment.Properties = map[string]*parsetype.Type{
+		"Reply": {Type: "ref", RefType: comment, Tags: `json:"reply"`},
+	}
+	thread := &parsetype.Type{Type: "struct", Properties: map[string]*parsetype.Type{
+		"First": comment,
+	}}
+
+	def = &Schema{}
+	parser.parseDefinitionModel(def, thread)
+	if ref := def.Properties["First"].Ref; ref != "#/definitions/pkg.Comment" {
+		t.Errorf("inline recursive struct was not hoisted: %q", ref)
+	}
+	if hoisted := parser.swagger.Definitions["pkg.Comment"]; hoisted == nil || hoisted.Properties["reply"] == nil {
+		t.Errorf("hoisted definition is incomplete: %v", hoisted)
+	}
+
+	cycles := parser.Cycles()
+	if len(cycles) != 2 || cycles[0] != "pkg.Node -> pkg.Node" || cycles[1] != "pkg.Comment -> pkg.Comment" {
+		t.Errorf("unexpected cycles %v", cycles)
+	}
+}
+
+func TestInlineStruct(t *testing.T) {
+	src := `package models
+
+type User struct {
+	Address struct {
+		// @Description street and number
+		// @Required
+		Street string ` + "`json:\"street\"`" + `
+		Zip    *string ` + "`json:\"zip,omitempty\"`" + `
+	} ` + "`json:\"address\"`" + `
+	Phones []struct{ Number string }
+	Labels map[string]struct{ Color string }
+}
+`
+	file, err := goparser.ParseFile(token.NewFileSet(), "models.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+
+	def := parser.structSchema(st, file, "github.com/org/models", "github.com/org/models.User")
+	address := def.Properties["address"]
+	if address == nil || address.Type != "object" || address.Properties["street"] == nil {
+		t.Fatalf("unexpected address schema %v", address)
+	}
+	if strings.TrimSpace(address.Properties["street"].Description) != "street and number" || len(address.Required) != 1 {
+		t.Errorf("annotations on inline fields were lost: %v", address)
+	}
+	if !address.Properties["zip"].Nullable {
+		t.Error("inline pointer field should be nullable")
+	}
+	if items := def.Properties["Phones"].Items; items == nil || items.Properties["Number"] == nil {
+		t.Errorf("unexpected slice of struct schema %v", def.Properties["Phones"])
+	}
+	if values := def.Properties["Labels"].AdditionalProperties; values == nil || values.Properties["Color"] == nil {
+		t.Errorf("unexpected map of struct schema %v", def.Properties["Labels"])
+	}
+
+	parser.HoistInline = true
+	parser.structSchema(st, file, "github.com/org/models", "github.com/org/models.User")
+	def = parser.swagger.Definitions["github.com.org.models.User"]
+	if ref := def.Properties["address"].Ref; ref != "#/definitions/github.com.org.models.UserAddress" {
+		t.Errorf("inline struct was not hoisted: %q", ref)
+	}
+	if ref := def.Properties["Phones"].Items.Ref; ref != "#/definitions/github.com.org.models.UserPhones" {
+		t.Errorf("slice of inline struct was not hoisted: %q", ref)
+	}
+	if hoisted := parser.swagger.Definitions["github.com.org.models.UserAddress"]; hoisted == nil || hoisted.Properties["street"] == nil {
+		t.Errorf("unexpected hoisted definition %v", hoisted)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	str, num := &Schema{Type: "string"}, &Schema{Type: "integer"}
+	def := &Schema{Properties: make(map[string]*Schema)}
+	promote(def, []*jsonField{
+		// the shallowest field wins
+		{name: "id", depth: 1, tagged: true, schema: str},
+		{name: "id", depth: 0, schema: num, required: true},
+		// at the same depth the only tagged field wins
+		{name: "name", depth: 1, schema: num},
+		{name: "name", depth: 1, tagged: true, schema: str},
+		// otherwise the name is dropped
+		{name: "kind", depth: 1, tagged: true, schema: str},
+		{name: "kind", depth: 1, tagged: true, schema: num},
+		{name: "size", depth: 2, schema: str},
+		{name: "size", depth: 2, schema: num},
+	})
+
+	if def.Properties["id"] != num || def.Properties["name"] != str {
+		t.Errorf("unexpected properties %v", def.Properties)
+	}
+	if _, ok := def.Properties["kind"]; ok {
+		t.Error("kind is tagged twice at the same depth and should be dropped")
+	}
+	if _, ok := def.Properties["size"]; ok {
+		t.Error("size is untagged twice at the same depth and should be dropped")
+	}
+	if len(def.Required) != 1 || def.Required[0] != "id" {
+		t.Errorf("unexpected required %v", def.Required)
+	}
+}
+
+func TestInlineStructTags(t *testing.T) {
+	src := `package models
+
+type User struct {
+	Address struct {
+		Street string ` + "`json:\"street\" arlong:\"required,maxLength=40\"`" + `
+		Code   string ` + "`json:\"-\"`" + `
+		Note   string ` + "`json:\",omitempty\"`" + `
+		secret string
+	} ` + "`json:\"address\"`" + `
+}
+`
+	file, err := goparser.ParseFile(token.NewFileSet(), "models.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.models = make(map[string]*modelName)
+	parser.sources = make(map[string][]*ast.File)
+
+	address := parser.structSchema(st, file, "github.com/org/models", "github.com/org/models.User").Properties["address"]
+	if street := address.Properties["street"]; street == nil || street.MaxLength != 40 {
+		t.Errorf("arlong tags on inline fields were lost: %v", street)
+	}
+	if len(address.Required) != 1 || address.Required[0] != "street" {
+		t.Errorf("unexpected required %v", address.Required)
+	}
+	if _, ok := address.Properties["Note"]; !ok {
+		t.Error("a json tag without a name should keep the field name")
+	}
+	for _, name := range []string{"Code", "secret"} {
+		if _, ok := address.Properties[name]; ok {
+			t.Errorf("%s is not encoded and should be skipped", name)
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+
+	op := &Operation{}
+	parser.parseResponseExample(op, testAnnotation("@Example", `200 json {"id": 7, "name": "Jane"}`))
+	resp := &Responses{Examples: op.Responses["200"].Examples}
+	ann := testAnnotation("@Response", `desc=ok schema.$ref=User`)
+	parser.parseResponse(resp, ann.values(0), ann)
+	op.Responses["200"] = resp
+	parser.swagger.Paths["/users/{id}"] = &Path{GET: op}
+
+	param := &Parameter{}
+	ann = testAnnotation("@Param", `name=id in=path type=integer example=42`)
+	parser.parseParam(param, ann.values(0), ann)
+	op.Parameters = []*Parameter{param}
+
+	parser.swagger.Definitions["User"] = &Schema{
+		Type:     "object",
+		Required: []string{"id"},
+		Properties: map[string]*Schema{
+			"id":   {Type: "integer"},
+			"name": {Type: "string", Example: 12},
+		},
+	}
+
+	example, ok := resp.Examples[MIME_JSON].(map[string]interface{})
+	if !ok || example["id"] != float64(7) {
+		t.Fatalf("response example was not parsed as JSON: %#v", resp.Examples)
+	}
+	if param.Example != float64(42) {
+		t.Errorf("parameter example was not typed: %#v", param.Example)
+	}
+
+	problems := parser.validateExamples()
+	if len(problems) != 1 || !strings.Contains(problems[0], "expected a string") {
+		t.Errorf("unexpected validation problems %v", problems)
+	}
+
+	parser.readZone([]*ast.Comment{
+		{Text: "// @Definition Code"},
+		{Text: "// @Example 123"},
+		{Text: "// @Type string"},
+		{Text: `// @Property enabled type=boolean example=true`},
+		{Text: `// @Property zip type=string example=01234`},
+		{Text: `// @Property note type=string example=null`},
+		{Text: "//"},
+	})
+	parser.typeExamples()
+	code := parser.swagger.Definitions["Code"]
+	if code.Example != "123" || code.Properties["enabled"].Example != true || code.Properties["zip"].Example != "01234" || code.Properties["note"].Example != "null" {
+		t.Errorf("examples were not typed: %#v %#v %#v %#v", code.Example, code.Properties["enabled"].Example, code.Properties["zip"].Example, code.Properties["note"].Example)
+	}
+}
+
+func TestDescription(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	doc := "# Users\r\n\n| Name | Type |\n|---|---|\n| id | int |\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "docs", "users.md"), []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := `package api
+
+// @Path /users
+// @Method GET
+// @Description Lists users.
+// @Description
+// @Description Filters:
+//     - ` + "`name`" + ` matches a prefix
+//       of the name
+//     - ` + "`tag`" + ` matches exactly
+// @Summary List users
+//
+func listUsers() {}
+
+// @Path /users/{id}
+// @Method GET
+// @Description file=docs/users.md
+//
+func getUser() {}
+
+// @Path /users/{id}
+// @Method DELETE
+// @Description file=missing.md
+//
+func deleteUser() {}
+`
+	filename := filepath.Join(dir, "api.go")
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, filename, src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.fset = fset
+	for _, group := range file.Comments[:2] {
+		parser.readZone(group.List)
+	}
+
+	want := "Lists users.\n\nFilters:\n- `name` matches a prefix\n  of the name\n- `tag` matches exactly"
+	if got := parser.swagger.Paths["/users"].GET.Description; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := parser.swagger.Paths["/users"].GET.Summary; got != "List users" {
+		t.Errorf("an annotation should end the description, got summary %q", got)
+	}
+	if got, want := parser.swagger.Paths["/users/{id}"].GET.Description, "# Users\n\n| Name | Type |\n|---|---|\n| id | int |"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	func() {
+		defer func() {
+			err, ok := recover().(*SyntaxError)
+			if !ok || err.Position.Filename != filename || err.Position.Column != 17 || !strings.Contains(err.Msg, "cannot read the description") {
+				t.Errorf("expected an error for the missing file, got %v", err)
+			}
+		}()
+		parser.readZone(file.Comments[2].List)
+	}()
+}
+
+func TestMixins(t *testing.T) {
+	src := `package api
+
+// @Mixin errors
+// @Description Fails with the usual errors.
+// @Response 401 "Unauthorized"
+// @Response 500 "Internal error"
+// @Security apiKey
+//
+
+// @Mixin guide
+// @Description See the guide.
+//
+
+// @Mixin paging
+// @Param page query int "Page number" default=1
+// @Param size query int "Page size" default=20
+// @Use errors
+//
+
+// @Path /users
+// @Method GET
+// @Use paging
+// @Summary List users
+// @Description Lists users.
+// @Description Newest first.
+// @Security apiKey
+// @Param size query int "Page size" maximum=100
+// @Response 401 "Sign in first"
+//
+func listUsers() {}
+
+// @Path /groups
+// @Method GET
+// @Use errors
+//
+func listGroups() {}
+
+// @Path /pets
+// @Method GET
+// @Description Lists pets.
+// @Use guide
+// @Description Newest first.
+//
+func listPets() {}
+
+// @Path /tags
+// @Method GET
+// @Use errors guide
+//
+func listTags() {}
+`
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "api.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.fset = fset
+	for _, read := range []func([]*ast.Comment){parser.readMixins, parser.readZone} {
+		for _, group := range file.Comments {
+			read(group.List)
+		}
+	}
+
+	op := parser.swagger.Paths["/users"].GET
+	if len(op.Parameters) != 2 || op.Parameters[0].Name != "page" || op.Parameters[1].Maximum != 100 || op.Parameters[1].Default != nil {
+		t.Errorf("expected the size parameter of the operation to replace the mixin's, got %#v", op.Parameters)
+	}
+	if op.Responses["401"].Description != "Sign in first" || op.Responses["500"].Description != "Internal error" {
+		t.Errorf("unexpected responses %#v", op.Responses)
+	}
+	if len(op.Security) != 1 || op.Security[0]["apiKey"] == nil || op.Summary != "List users" {
+		t.Errorf("unexpected operation %#v", op)
+	}
+	if op.Description != "Lists users.\nNewest first." {
+		t.Errorf("expected the operation description to replace the mixin's, got %q", op.Description)
+	}
+	if groups := parser.swagger.Paths["/groups"].GET; groups.Description != "Fails with the usual errors." {
+		t.Errorf("expected the mixin description, got %q", groups.Description)
+	}
+	if pets := parser.swagger.Paths["/pets"].GET; pets.Description != "Lists pets.\nNewest first." {
+		t.Errorf("expected the operation description around the @Use, got %q", pets.Description)
+	}
+	if tags := parser.swagger.Paths["/tags"].GET; tags.Description != "Fails with the usual errors.\nSee the guide." {
+		t.Errorf("expected the descriptions of both mixins, got %q", tags.Description)
+	}
+
+	const loops = `
+// @Mixin loop
+// @Use again
+//
+
+// @Mixin again
+// @Use loop
+//
+`
+	for block, want := range map[string]string{
+		"// @Path /x\n// @Method GET\n// @Use missing":    "api.go:13:9: @Use: unknown mixin missing",
+		"// @Path /x\n// @Method GET\n// @Use again":      "@Use: mixin cycle again -> loop -> again",
+		"// @Path /x\n// @Summary Early\n// @Method GET":  "@Summary: expected a @Method first",
+		"// @Mixin loop\n// @Use again":                   "api.go:11:11: @Mixin: mixin loop is already declared at api.go:3:11",
+		"// @Mixin broken\n// @Path /x":                   "@Path: only operation annotations can be used in a mixin",
+		"// @Path /x\n// @Method GET\n// @Use loop=again": "unknown mixin loop=again",
+	} {
+		func() {
+			defer func() {
+				if err, ok := recover().(*SyntaxError); !ok || !strings.Contains(err.Error(), want) {
+					t.Errorf("%q: expected an error containing %q, got %v", block, want, err)
+				}
+			}()
+
+			fset := token.NewFileSet()
+			file, err := goparser.ParseFile(fset, "api.go", "package api\n"+loops+"\n"+block+"\n//\n", goparser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := NewParser("")
+			parser.swagger = New()
+			parser.fset = fset
+			for _, read := range []func([]*ast.Comment){parser.readMixins, parser.readZone} {
+				for _, group := range file.Comments {
+					read(group.List)
+				}
+			}
+		}()
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	swagger := New()
+	swagger.Info = Info{
+		Title:       "Pet store",
+		Description: "Sell pets.\n\nSee *the docs*.",
+		Version:     "1.0.0",
+		Contact:     &Contact{Name: "Jane Doe", Email: "jane@example.com"},
+		License:     &License{Name: "Apache 2.0", URL: "http://www.apache.org/licenses/LICENSE-2.0"},
+	}
+	swagger.Host = "pets.example.com"
+	swagger.BasePath = "/v1"
+	swagger.Schemes = []string{"https"}
+	swagger.Produces = []string{MIME_JSON}
+	swagger.Security = []map[string][]string{{"apiKey": {}}}
+	swagger.Tags = []*Tag{{Name: "pets", Description: "Everything about pets"}}
+	swagger.SecurityDefinitions["apiKey"] = &SecurityDefinitions{Type: "apiKey", Name: "X-Key", In: "header"}
+	swagger.SecurityDefinitions["oauth"] = &SecurityDefinitions{
+		Type:     "oauth2",
+		Flow:     "password",
+		TokenUrl: "https://example.com/token",
+		Scopes:   map[string]string{"read:pets": "read your pets", "write:pets": "modify pets"},
+	}
+	swagger.Parameters["limit"] = &Parameter{Name: "limit", In: "query", Type: "integer", Format: INT32, Maximum: 100, Default: "20"}
+	swagger.Responses["notFound"] = &Responses{Description: "Not found", Schema: &Schema{Ref: "#/definitions/Error"}}
+	swagger.Definitions["Error"] = &Schema{
+		Type:     "object",
+		Required: []string{"message"},
+		Properties: map[string]*Schema{
+			"message": {Type: "string", Description: "What went\nwrong", Example: `no such "pet"`},
+		},
+	}
+	swagger.Definitions["Pet"] = &Schema{
+		Type:        "object",
+		Description: "A pet for sale",
+		Required:    []string{"id", "name"},
+		Properties: map[string]*Schema{
+			"id":      {Type: "integer", Format: INT64, Minimum: 1},
+			"name":    {Type: "string", MaxLength: 50},
+			"status":  {Type: "string", Enum: []string{"available", "sold"}},
+			"tags":    {Type: "array", Items: &Schema{Type: "string"}},
+			"born":    {Type: "string", Format: "date-time", Nullable: true},
+			"owner":   {Ref: "#/definitions/Owner"},
+			"weight":  {Type: "number", Format: FLOAT, Example: 4.5},
+			"details": {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"city":    {Type: "string", Enum: []string{"New York", "Paris"}},
+		},
+	}
+	swagger.Definitions["Owner"] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"name": {Type: "string"}},
+	}
+	swagger.Definitions["Status"] = &Schema{Type: "string", Enum: []string{"available", "sold"}}
+	swagger.Paths["/pets"] = &Path{
+		GET: &Operation{
+			Summary:     "List pets",
+			Description: "Lists pets.\n\n| Status | Meaning |\n|---|---|\n| sold | gone |\n\n```json\n{\n  \"id\": 1\n}\n```",
+			OperationId: "listPets",
+			Tags:        []string{"pets"},
+			Parameters: []*Parameter{
+				{Ref: "#/parameters/limit"},
+				{Name: "status", In: "query", Type: "array", CollectionFormat: "multi", Items: &Items{Type: "string", Default: "sold", MinItems: 1, Enum: []string{"available", "sold"}}},
+			},
+			Responses: map[string]*Responses{
+				"200": {
+					Description: "The pets",
+					Schema:      &Schema{Type: "array", Items: &Schema{Ref: "#/definitions/Pet"}},
+					Examples:    map[string]interface{}{MIME_JSON: []interface{}{map[string]interface{}{"id": float64(1), "name": "Rex"}}},
+				},
+			},
+		},
+		POST: &Operation{
+			OperationId: "createPet",
+			Consumes:    []string{MIME_JSON},
+			Security:    []map[string][]string{{"oauth": {"write:pets"}}},
+			Parameters: []*Parameter{
+				{Name: "pet", In: "body", Required: true, Schema: &Schema{Ref: "#/definitions/Pet"}},
+			},
+			Responses: map[string]*Responses{
+				"201":     {Description: "Created"},
+				"default": {Description: "Unexpected error", Schema: &Schema{Ref: "#/definitions/Error"}},
+			},
+		},
+	}
+	swagger.Paths["/pets/{id}"] = &Path{
+		GET: &Operation{
+			OperationId: "getPet",
+			Deprecated:  true,
+			Security:    []map[string][]string{{"apiKey": {}}},
+			Parameters: []*Parameter{
+				{Name: "id", In: "path", Required: true, Type: "string", Format: "uuid", Example: "42"},
+			},
+			Responses: map[string]*Responses{
+				"200": {Description: "The pet", Schema: &Schema{Ref: "#/definitions/Pet"}},
+				"404": {Ref: "#/responses/notFound"},
+			},
+		},
+	}
+
+	src, err := Annotate(swagger, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "annotations.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, src)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	for _, group := range file.Comments {
+		parser.readZone(group.List)
+	}
+	parser.typeExamples()
+
+	// additionalProperties and enums with spaces cannot be written as
+	// annotations
+	swagger.Definitions["Pet"].Properties["details"].AdditionalProperties = nil
+	swagger.Definitions["Pet"].Properties["city"].Enum = nil
+	got, _ := json.MarshalIndent(parser.swagger, "", "  ")
+	want, _ := json.MarshalIndent(swagger, "", "  ")
+	if string(got) != string(want) {
+		t.Errorf("round trip changed the document:\n%s\nwant\n%s\nfrom\n%s", got, want, src)
+	}
+
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{"type Pet struct", "Born *time.Time `json:\"born,omitempty\"`", "Owner Owner `json:\"owner,omitempty\"`"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "type Status struct") {
+		t.Errorf("unexpected skeleton for a string definition\n%s", src)
+	}
+}
+
+func TestSwaggo(t *testing.T) {
+	src := `package api
+
+import "github.com/org/svc/model"
+
+// @title Accounts API
+// @version 1.0
+// @description Manage accounts.
+// @host accounts.example.com
+// @BasePath /api/v1
+// @accept json
+// @produce json xml
+// @tag.name accounts
+// @tag.description Account operations
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @securitydefinitions.oauth2.password OAuth2Password
+// @tokenUrl https://example.com/oauth/token
+// @scope.write Grants write access
+func main() {}
+
+// ShowAccount godoc
+// @Summary      Show an account
+// @Description  get account by ID
+// @Tags         accounts
+// @ID           showAccount
+// @Produce      json
+// @Param        id    path     int     true  "Account ID"  minimum(1)
+// @Param        q     query    string  false "name search" enums(a,b) default(a)
+// @Param        ids   query    []int   false "filter ids"
+// @Success      200  {object}  model.Account
+// @Success      206  {object}  Page{data=[]model.Account,total=int}  "Partial"
+// @Failure      400,404  {object}  model.HTTPError
+// @Header       200  {string}  Token  "qwerty"
+// @Security     ApiKeyAuth || OAuth2Password[write, admin]
+// @Router       /accounts/{id} [get]
+func ShowAccount() {}
+
+// AddAccount godoc
+// @Summary  Add an account
+// @Accept   json
+// @Param    account  body  model.AddAccount  true  "Add account"
+// @Success  201  {array}  model.Account
+// @Router   /accounts [post]
+func AddAccount() {}
+`
+	file, err := goparser.ParseFile(token.NewFileSet(), "api.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.file, parser.pkgPath = file, "github.com/org/svc/api"
+	for _, group := range file.Comments {
+		parser.readZone(group.List)
+	}
+	swagger := parser.swagger
+
+	if swagger.Info.Title != "Accounts API" || swagger.Host != "accounts.example.com" || swagger.BasePath != "/api/v1" {
+		t.Errorf("unexpected general info %#v", swagger)
+	}
+	if len(swagger.Produces) != 2 || swagger.Produces[1] != "application/xml" {
+		t.Errorf("unexpected produces %v", swagger.Produces)
+	}
+	if len(swagger.Tags) != 1 || swagger.Tags[0].Description != "Account operations" {
+		t.Errorf("unexpected tags %#v", swagger.Tags)
+	}
+	if def := swagger.SecurityDefinitions["ApiKeyAuth"]; def == nil || def.Type != "apiKey" || def.In != "header" || def.Name != "Authorization" {
+		t.Errorf("unexpected api key definition %#v", def)
+	}
+	if def := swagger.SecurityDefinitions["OAuth2Password"]; def == nil || def.Flow != "password" || def.Scopes["write"] != "Grants write access" {
+		t.Errorf("unexpected oauth2 definition %#v", def)
+	}
+
+	op := swagger.Paths["/accounts/{id}"].GET
+	if op == nil || op.OperationId != "showAccount" || op.Tags[0] != "accounts" {
+		t.Fatalf("unexpected operation %#v", op)
+	}
+	if id := op.Parameters[0]; id.In != "path" || !id.Required || id.Type != "integer" || id.Minimum != 1 || id.Description != "Account ID" {
+		t.Errorf("unexpected id parameter %#v", id)
+	}
+	if q := op.Parameters[1]; q.Required || len(q.Enum) != 2 || q.Default != "a" {
+		t.Errorf("unexpected q parameter %#v", q)
+	}
+	if ids := op.Parameters[2]; ids.Type != "array" || ids.Items.Type != "integer" {
+		t.Errorf("unexpected ids parameter %#v", ids)
+	}
+
+	if resp := op.Responses["200"]; resp.Schema.Ref != "#/definitions/github.com.org.svc.model.Account" || resp.Description != "OK" {
+		t.Errorf("unexpected 200 response %#v", resp)
+	}
+	if resp := op.Responses["200"]; resp.Headers["Token"] == nil || resp.Headers["Token"].Type != "string" {
+		t.Errorf("unexpected 200 headers %#v", resp.Headers)
+	}
+	page := op.Responses["206"].Schema
+	if len(page.AllOf) != 2 || page.AllOf[0].RawRefName != "github.com/org/svc/api.Page" || page.AllOf[1].Properties["data"].Items.RawRefName != "github.com/org/svc/model.Account" || page.AllOf[1].Properties["total"].Type != "integer" {
+		t.Errorf("unexpected composed schema %#v", page)
+	}
+	if op.Responses["400"] == nil || op.Responses["404"].Description != "Not Found" {
+		t.Errorf("unexpected failure responses %#v", op.Responses)
+	}
+	if len(op.Security) != 2 || op.Security[1]["OAuth2Password"][1] != "admin" {
+		t.Errorf("unexpected security %v", op.Security)
+	}
+
+	add := swagger.Paths["/accounts"].POST
+	if add == nil || add.Parameters[0].Schema.RawRefName != "github.com/org/svc/model.AddAccount" || add.Consumes[0] != MIME_JSON {
+		t.Fatalf("unexpected add operation %#v", add)
+	}
+	if resp := add.Responses["201"]; resp.Schema.Type != "array" || resp.Schema.Items.RawRefName != "github.com/org/svc/model.Account" {
+		t.Errorf("unexpected 201 response %#v", resp)
+	}
+
+	for line, want := range map[string]string{
+		"@Router /a get":                            "api.go:2:12: @Router: expected a path and a [method]",
+		"@Router /a [fetch]":                        "api.go:2:15: @Router: unsupported method FETCH",
+		"@Param id path int yes":                    "api.go:2:23: @Param: required must be true or false",
+		`@Param id path int true "ID" minimum(1.5)`: `api.go:2:33: @Param: minimum must be an integer, not "1.5"`,
+		`@Param id path int true "ID" bogus`:        `api.go:2:33: @Param: expected an attribute such as default(...), got "bogus"`,
+		"@Success 200 {object}":                     "api.go:2:17: @Success: expected a type after {object}",
+		"@Success 200 {object} Page{data}":          "api.go:2:26: @Success: expected field=type in the composed type Page{data}",
+		"@Header 200 Token":                         "@Header: expected code[,code...]|all {type} name [description]",
+	} {
+		func() {
+			defer func() {
+				if err, ok := recover().(*SyntaxError); !ok || !strings.Contains(err.Error(), want) {
+					t.Errorf("%s: expected an error containing %q, got %v", line, want, err)
+				}
+			}()
+
+			fset := token.NewFileSet()
+			file, err := goparser.ParseFile(fset, "api.go", "package api\n// "+line+"\n// @Router /x [get]\nfunc F() {}\n", goparser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := NewParser("")
+			parser.swagger = New()
+			parser.fset, parser.file = fset, file
+			parser.readZone(file.Comments[0].List)
+		}()
+	}
+
+	func() {
+		defer func() {
+			if err, ok := recover().(*SyntaxError); !ok || !strings.Contains(err.Error(), "unsupported security definition type jwt") {
+				t.Errorf("expected an unsupported security definition error, got %v", err)
+			}
+		}()
+		parser := NewParser("")
+		parser.swagger = New()
+		parser.readZone([]*ast.Comment{{Text: "// @title API"}, {Text: "// @securityDefinitions.jwt Token"}})
+	}()
+}
+
+func TestShorthand(t *testing.T) {
+	parser := NewParser("")
+	parser.swagger = New()
+	parser.readZone([]*ast.Comment{
+		{Text: "// @Path /users/{id}"},
+		{Text: "// @Method GET"},
+		{Text: `// @Param id path int64 required "The user id" minimum=1`},
+		{Text: `// @Param tags query []string "Filter by tags"`},
+		{Text: `// @Param user body []pkg.User required`},
+		{Text: `// @Param required name=legacy in=query type=string`},
+		{Text: `// @Param required deprecated name=old in=query`},
+		{Text: `// @Response 200 {array} pkg.User "List of users"`},
+		{Text: `// @Response 201 User "Created"`},
+		{Text: `// @Response 204 "No content"`},
+		{Text: `// @Response 400 {string} string`},
+		{Text: `// @Response 404 $ref=notFound`},
+		{Text: "//"},
+	})
+
+	op := parser.swagger.Paths["/users/{id}"].GET
+	id := op.Parameters[0]
+	if id.Name != "id" || id.In != "path" || id.Type != "integer" || id.Format != INT64 || !id.Required || id.Description != "The user id" || id.Minimum != 1 {
+		t.Errorf("unexpected id parameter %#v", id)
+	}
+	if tags := op.Parameters[1]; tags.Type != "array" || tags.Items.Type != "string" || tags.Required || tags.Description != "Filter by tags" {
+		t.Errorf("unexpected tags parameter %#v", tags)
+	}
+	if user := op.Parameters[2]; user.Schema == nil || user.Schema.Type != "array" || user.Schema.Items.Ref != "#/definitions/pkg.User" {
+		t.Errorf("unexpected body parameter %#v", user)
+	}
+	if legacy := op.Parameters[3]; legacy.Name != "legacy" || !legacy.Required {
+		t.Errorf("unexpected key=value parameter %#v", legacy)
+	}
+	if old := op.Parameters[4]; old.Name != "old" || old.In != "query" || !old.Required {
+		t.Errorf("unexpected key=value parameter with flags %#v", old)
+	}
+
+	if resp := op.Responses["200"]; resp.Description != "List of users" || resp.Schema.Type != "array" || resp.Schema.Items.Ref != "#/definitions/pkg.User" {
+		t.Errorf("unexpected 200 response %#v", resp)
+	}
+	if resp := op.Responses["201"]; resp.Description != "Created" || resp.Schema.Ref != "#/definitions/User" {
+		t.Errorf("unexpected 201 response %#v", resp)
+	}
+	if resp := op.Responses["204"]; resp.Description != "No content" || resp.Schema != nil {
+		t.Errorf("unexpected 204 response %#v", resp)
+	}
+	if resp := op.Responses["400"]; resp.Schema.Type != "string" {
+		t.Errorf("unexpected 400 response %#v", resp)
+	}
+	if resp := op.Responses["404"]; resp.Ref != "#/responses/notFound" {
+		t.Errorf("unexpected 404 response %#v", resp)
+	}
+
+	for line, want := range map[string]string{
+		`id path`:                      "expected name in type",
+		`id cookie string`:             `"cookie" is not a parameter location`,
+		`id query pkg.User`:            "need a primitive type",
+		`id query string required Bad`: `unexpected "Bad"`,
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil || !strings.Contains(fmt.Sprint(err), want) {
+					t.Errorf("@Param %s: expected an error containing %q, got %v", line, want, err)
+				}
+			}()
+			paramValues(testAnnotation("@Param", line), 0)
+		}()
+	}
+
+	for line, want := range map[string]string{
+		`{array}`:              "expected [{kind}] [type]",
+		`{integer} pkg.User`:   "{integer} does not match pkg.User",
+		`{object} pkg.User OK`: `unexpected "OK"`,
+		`OK`:                   `"OK" is not a type`,
+		`Created desc=x`:       `"Created" is not a type`,
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil || !strings.Contains(fmt.Sprint(err), want) {
+					t.Errorf("@Response %s: expected an error containing %q, got %v", line, want, err)
+				}
+			}()
+			responseValues(testAnnotation("@Response", line), 0)
+		}()
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for block, want := range map[string]string{
+		"// @Swagger\n// @Tag":                                                 "api.go:4:8: @Tag: expected a tag name",
+		"// @Path /x\n// @Method FETCH":                                        "api.go:4:12: @Method: unsupported method FETCH",
+		"// @Path /x\n// @Method GET\n// @Param $ref=missing":                  "api.go:5:11: @Param: unknown global parameter missing",
+		"// @Path /x\n// @Method GET\n// @Response 404 $ref=gone":              "api.go:5:18: @Response: unknown global response gone",
+		"// @Path /x\n// @Method GET\n// @Param id query int maximum=1.5":      `api.go:5:24: @Param: maximum must be an integer, not "1.5"`,
+		"// @Definition User\n// @Property name type=string items.minLength=x": `api.go:4:31: @Property: items.minLength must be an integer, not "x"`,
+		"// @Path /x\n// @Method GET\n// @Example 200 json missing.json":       "api.go:5:22: @Example: cannot read the example",
+	} {
+		func() {
+			defer func() {
+				if err, ok := recover().(*SyntaxError); !ok || !strings.Contains(err.Error(), want) {
+					t.Errorf("%q: expected an error containing %q, got %v", block, want, err)
+				}
+			}()
+
+			fset := token.NewFileSet()
+			file, err := goparser.ParseFile(fset, "api.go", "package api\n\n"+block+"\n//\n", goparser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := NewParser("")
+			parser.swagger = New()
+			parser.fset = fset
+			parser.readZone(file.Comments[0].List)
+			parser.validate()
+		}()
+	}
 }
@@ -0,0 +1,191 @@
+package spec
+
+import (
+	. "github.com/peak6/arlong/schema"
+	"sort"
+	"strings"
+)
+
+// Unused lists the components of a document that no operation refers to.
+type Unused struct {
+	Definitions         []string
+	Parameters          []string
+	Responses           []string
+	SecurityDefinitions []string
+}
+
+func (u *Unused) Empty() bool {
+	return len(u.Definitions) == 0 && len(u.Parameters) == 0 && len(u.Responses) == 0 && len(u.SecurityDefinitions) == 0
+}
+
+type reachability struct {
+	swagger             *Swagger
+	definitions         map[string]struct{}
+	parameters          map[string]struct{}
+	responses           map[string]struct{}
+	securityDefinitions map[string]struct{}
+}
+
+func newReachability(swagger *Swagger) *reachability {
+	return &reachability{
+		swagger:             swagger,
+		definitions:         make(map[string]struct{}),
+		parameters:          make(map[string]struct{}),
+		responses:           make(map[string]struct{}),
+		securityDefinitions: make(map[string]struct{}),
+	}
+}
+
+func (r *reachability) markSchema(s *Schema) {
+	walkSchema(s, func(s *Schema) {
+		if !strings.HasPrefix(s.Ref, "#/definitions/") {
+			return
+		}
+		r.markDefinition(removeDefinitionRef(s.Ref))
+	})
+}
+
+func (r *reachability) markDefinition(name string) {
+	if _, ok := r.definitions[name]; ok {
+		return
+	}
+
+	r.definitions[name] = struct{}{}
+	r.markSchema(r.swagger.Definitions[name])
+}
+
+func (r *reachability) markParameter(param *Parameter) {
+	if param.Ref != "" {
+		name := strings.TrimPrefix(param.Ref, "#/parameters/")
+		if _, ok := r.parameters[name]; ok {
+			return
+		}
+		r.parameters[name] = struct{}{}
+		if global := r.swagger.Parameters[name]; global != nil {
+			r.markParameter(global)
+		}
+		return
+	}
+
+	r.markSchema(param.Schema)
+}
+
+func (r *reachability) markResponse(resp *Responses) {
+	if resp.Ref != "" {
+		name := strings.TrimPrefix(resp.Ref, "#/responses/")
+		if _, ok := r.responses[name]; ok {
+			return
+		}
+		r.responses[name] = struct{}{}
+		if global := r.swagger.Responses[name]; global != nil {
+			r.markResponse(global)
+		}
+		return
+	}
+
+	r.markSchema(resp.Schema)
+}
+
+func (r *reachability) markSecurity(security []map[string][]string) {
+	for _, requirement := range security {
+		for name := range requirement {
+			r.securityDefinitions[name] = struct{}{}
+		}
+	}
+}
+
+// mark walks everything reachable from the paths, the root security
+// requirements and the extra definition roots.
+func (r *reachability) mark(roots []string) {
+	r.markSecurity(r.swagger.Security)
+
+	for _, path := range r.swagger.Paths {
+		for i := range path.Parameters {
+			r.markParameter(&path.Parameters[i])
+		}
+
+		for _, op := range path.Operations() {
+			for _, param := range op.Parameters {
+				r.markParameter(param)
+			}
+			for _, resp := range op.Responses {
+				r.markResponse(resp)
+			}
+			r.markSecurity(op.Security)
+		}
+	}
+
+	for _, root := range roots {
+		if _, ok := r.swagger.Definitions[root]; ok {
+			r.markDefinition(root)
+		}
+	}
+}
+
+func (r *reachability) unused() *Unused {
+	unused := &Unused{}
+	for name := range r.swagger.Definitions {
+		if _, ok := r.definitions[name]; !ok {
+			unused.Definitions = append(unused.Definitions, name)
+		}
+	}
+	for name := range r.swagger.Parameters {
+		if _, ok := r.parameters[name]; !ok {
+			unused.Parameters = append(unused.Parameters, name)
+		}
+	}
+	for name := range r.swagger.Responses {
+		if _, ok := r.responses[name]; !ok {
+			unused.Responses = append(unused.Responses, name)
+		}
+	}
+	for name := range r.swagger.SecurityDefinitions {
+		if _, ok := r.securityDefinitions[name]; !ok {
+			unused.SecurityDefinitions = append(unused.SecurityDefinitions, name)
+		}
+	}
+
+	sort.Strings(unused.Definitions)
+	sort.Strings(unused.Parameters)
+	sort.Strings(unused.Responses)
+	sort.Strings(unused.SecurityDefinitions)
+
+	return unused
+}
+
+// findUnused reports the components that cannot be reached from the paths
+// or the parser roots.
+func (p *Parser) findUnused() *Unused {
+	r := newReachability(p.swagger)
+	r.mark(p.Roots)
+
+	return r.unused()
+}
+
+// prune removes every component listed in unused from the document.
+func (p *Parser) prune(unused *Unused) {
+	for _, name := range unused.Definitions {
+		delete(p.swagger.Definitions, name)
+	}
+	for _, name := range unused.Parameters {
+		delete(p.swagger.Parameters, name)
+	}
+	for _, name := range unused.Responses {
+		delete(p.swagger.Responses, name)
+	}
+	for _, name := range unused.SecurityDefinitions {
+		delete(p.swagger.SecurityDefinitions, name)
+	}
+}
+
+// Unused returns the definitions, parameters, responses and security
+// definitions that no operation uses. It is computed before pruning.
+func (p *Parser) Unused() (*Unused, error) {
+	if p.swagger == nil {
+		if err := p.Parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.unused, nil
+}
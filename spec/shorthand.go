@@ -0,0 +1,173 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The shorthand forms of @Param and @Response are positional:
+//
+//	@Param id path string required "The user id"
+//	@Response 200 {array} pkg.User "List of users"
+//
+// Both may end with key=value options. A @Param line is read as shorthand
+// when its second positional value is a location or it has no key=value
+// pair, so legacy lines such as "required name=id in=query" keep working. A
+// @Response line is read as shorthand when it does not start with key=value
+// pairs.
+
+var paramLocations = map[string]bool{
+	"query":    true,
+	"path":     true,
+	"header":   true,
+	"body":     true,
+	"formData": true,
+}
+
+// optionValues reads the trailing key=value options of ann.
+func optionValues(ann *annotation, args []*arg, vals map[string]string) {
+	for _, a := range args {
+		if a.key == "" {
+			panic(ann.errorAt(a.offset, fmt.Sprintf("unexpected %q, options after the positional values must be key=value", a.value)))
+		}
+
+		vals[a.key] = a.value
+	}
+}
+
+// typeValues writes the key=value equivalent of a type written in
+// shorthand: a primitive, []T or a definition name.
+func typeValues(vals map[string]string, prefix, typ string) {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		vals[prefix+"type"] = "array"
+		typeValues(vals, prefix+"items.", typ[2:])
+	case isPrimitive(typ):
+		vals[prefix+"type"] = typ
+	default:
+		vals[prefix+"$ref"] = typ
+	}
+}
+
+// isParamShorthand reports whether the args of a @Param are written in
+// shorthand.
+func isParamShorthand(args []*arg) bool {
+	positional := 0
+	for positional < len(args) && args[positional].key == "" {
+		positional++
+	}
+	if positional < 2 || args[0].quoted {
+		return false
+	}
+
+	return positional == len(args) || paramLocations[args[1].value]
+}
+
+// isTypeName reports whether a bare word of a @Response is clearly a type:
+// a primitive, a slice or a package qualified name.
+func isTypeName(word string) bool {
+	word = strings.TrimPrefix(word, "[]")
+	return isPrimitive(word) || strings.Contains(word, ".")
+}
+
+func isPrimitive(typ string) bool {
+	switch typ {
+	case "integer", "number", "boolean", "file":
+		return true
+	}
+
+	_, _, ok := getTypeFormat(typ)
+	return ok
+}
+
+// paramValues reads the values of a @Param annotation from its nth arg on,
+// either key=value pairs or the shorthand
+// "name in type [required|optional] [\"description\"] [key=value...]".
+func paramValues(ann *annotation, n int) map[string]string {
+	checkNumbers(ann)
+	args := ann.args()[n:]
+	if !isParamShorthand(args) {
+		return ann.values(n)
+	}
+
+	usage := "expected name in type [required] [\"description\"] or key=value pairs"
+	if len(args) < 3 || args[2].key != "" || args[2].quoted {
+		panic(ann.errorAt(args[0].offset, usage))
+	}
+
+	name, in, typ := args[0].value, args[1].value, args[2].value
+	if !paramLocations[in] {
+		panic(ann.errorAt(args[1].offset, fmt.Sprintf("%q is not a parameter location (query, path, header, body, formData)", in)))
+	}
+
+	vals := map[string]string{"name": name, "in": in}
+	if in == "body" {
+		typeValues(vals, "schema.", typ)
+	} else if element := strings.TrimPrefix(typ, "[]"); !isPrimitive(element) {
+		panic(ann.errorAt(args[2].offset, fmt.Sprintf("%s parameters need a primitive type, not %s", in, typ)))
+	} else {
+		typeValues(vals, "", typ)
+	}
+
+	rest := args[3:]
+	if len(rest) > 0 && rest[0].key == "" && !rest[0].quoted && (rest[0].value == "required" || rest[0].value == "optional") {
+		if rest[0].value == "required" {
+			vals["required"] = ""
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0].quoted && rest[0].key == "" {
+		vals["description"] = rest[0].value
+		rest = rest[1:]
+	}
+	optionValues(ann, rest, vals)
+
+	return vals
+}
+
+// responseValues reads the values of a @Response or @GlobalResponse
+// annotation from its nth arg on, either key=value pairs or the shorthand
+// "[{kind} type] [\"description\"] [key=value...]" where kind is object,
+// array or a primitive type.
+func responseValues(ann *annotation, n int) map[string]string {
+	args := ann.args()[n:]
+	if len(args) == 0 || args[0].key != "" {
+		return ann.values(n)
+	}
+
+	vals := map[string]string{}
+	rest := args
+	if a := rest[0]; !a.quoted && strings.HasPrefix(a.value, "{") {
+		if !strings.HasSuffix(a.value, "}") || len(rest) < 2 || rest[1].quoted || rest[1].key != "" {
+			panic(ann.errorAt(a.offset, "expected [{kind}] [type] [\"description\"] or key=value pairs"))
+		}
+
+		kind, typ := strings.Trim(a.value, "{}"), rest[1].value
+		switch {
+		case kind == "array":
+			vals["schema.type"] = "array"
+			typeValues(vals, "schema.items.", typ)
+		case kind == "object" || kind == typ || isPrimitive(kind) && isPrimitive(typ):
+			typeValues(vals, "schema.", typ)
+		default:
+			panic(ann.errorAt(a.offset, fmt.Sprintf("{%s} does not match %s", kind, typ)))
+		}
+		rest = rest[2:]
+	} else if !a.quoted {
+		// a bare word is a type only when it reads as one or a description
+		// follows it, so "@Response 200 OK" is not a $ref to OK
+		if !isTypeName(a.value) && (len(rest) < 2 || !rest[1].quoted || rest[1].key != "") {
+			panic(ann.errorAt(a.offset, fmt.Sprintf("%q is not a type, quote the description or write {object} %s", a.value, a.value)))
+		}
+		typeValues(vals, "schema.", a.value)
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 && rest[0].quoted && rest[0].key == "" {
+		vals["description"] = rest[0].value
+		rest = rest[1:]
+	}
+	optionValues(ann, rest, vals)
+
+	return vals
+}
@@ -0,0 +1,618 @@
+package spec
+
+import (
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	. "github.com/peak6/arlong/schema"
+	"go/ast"
+	"go/build"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Comment groups written for swaggo/swag are read by this file. A group is
+// in that dialect when it holds no arlong block and either routes an
+// operation with @Router or sets the general API info (@title,
+// @securityDefinitions.*, ...). Tags are matched case-insensitively like
+// swag does.
+
+// arlongBlocks are the tags that start an arlong annotation block.
+var arlongBlocks = map[string]bool{
+	"@Swagger":            true,
+	"@GlobalParam":        true,
+	"@SecurityDefinition": true,
+	"@GlobalResponse":     true,
+	"@Definition":         true,
+	"@Path":               true,
+	"@Mixin":              true,
+}
+
+// swaggoGeneral are the general API info tags that only swag uses.
+var swaggoGeneral = map[string]bool{
+	"@title":          true,
+	"@termsofservice": true,
+	"@contact.name":   true,
+	"@license.name":   true,
+	"@host":           true,
+}
+
+// swaggoMimes are the mime aliases of @Accept and @Produce.
+var swaggoMimes = map[string]string{
+	"json":                  MIME_JSON,
+	"xml":                   "application/xml",
+	"plain":                 "text/plain",
+	"html":                  "text/html",
+	"mpfd":                  "multipart/form-data",
+	"x-www-form-urlencoded": "application/x-www-form-urlencoded",
+	"json-api":              "application/vnd.api+json",
+	"json-stream":           "application/x-json-stream",
+	"octet-stream":          "application/octet-stream",
+	"png":                   "image/png",
+	"jpeg":                  "image/jpeg",
+	"gif":                   "image/gif",
+}
+
+// readSwaggo reads comments as swag annotations when they are written in
+// that dialect and reports whether it did.
+func (p *Parser) readSwaggo(comments []*ast.Comment) bool {
+	anns, _ := p.annotations(comments, false)
+	general, operation := false, false
+	for _, ann := range anns {
+		lower := strings.ToLower(ann.tag)
+		switch {
+		case arlongBlocks[ann.tag]:
+			return false
+		case lower == "@router":
+			operation = true
+		case swaggoGeneral[lower], strings.HasPrefix(lower, "@securitydefinitions."):
+			general = true
+		}
+	}
+
+	switch {
+	case operation:
+		p.parseSwaggoOperation(anns)
+	case general:
+		p.parseSwaggoGeneral(anns)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (p *Parser) parseSwaggoGeneral(anns []*annotation) {
+	var def *SecurityDefinitions
+	var tag *Tag
+	for _, ann := range anns {
+		name, val := ann.tag, ann.text
+		switch lower := strings.ToLower(name); {
+		case lower == "@title":
+			p.swagger.Info.Title = val
+		case lower == "@version":
+			p.swagger.Info.Version = val
+		case lower == "@description":
+			if def != nil {
+				def.Description = p.description(def.Description, ann)
+			} else {
+				p.swagger.Info.Description = p.description(p.swagger.Info.Description, ann)
+			}
+		case lower == "@termsofservice":
+			p.swagger.Info.TermsOfService = val
+		case strings.HasPrefix(lower, "@contact."):
+			if p.swagger.Info.Contact == nil {
+				p.swagger.Info.Contact = &Contact{}
+			}
+			switch lower {
+			case "@contact.name":
+				p.swagger.Info.Contact.Name = val
+			case "@contact.url":
+				p.swagger.Info.Contact.URL = val
+			case "@contact.email":
+				p.swagger.Info.Contact.Email = val
+			}
+		case strings.HasPrefix(lower, "@license."):
+			if p.swagger.Info.License == nil {
+				p.swagger.Info.License = &License{}
+			}
+			switch lower {
+			case "@license.name":
+				p.swagger.Info.License.Name = val
+			case "@license.url":
+				p.swagger.Info.License.URL = val
+			}
+		case lower == "@host":
+			p.swagger.Host = val
+		case lower == "@basepath":
+			p.swagger.BasePath = val
+		case lower == "@schemes":
+			p.swagger.Schemes = getValueStrings(val)
+		case lower == "@accept":
+			p.swagger.Consumes = swaggoMimeList(val)
+		case lower == "@produce":
+			p.swagger.Produces = swaggoMimeList(val)
+		case lower == "@tag.name":
+			tag = &Tag{Name: val}
+			p.swagger.Tags = append(p.swagger.Tags, tag)
+		case lower == "@tag.description" && tag != nil:
+			tag.Description = val
+		case lower == "@tag.docs.url" && tag != nil:
+			if tag.ExternalDocs == nil {
+				tag.ExternalDocs = &ExternalDocs{}
+			}
+			tag.ExternalDocs.URL = val
+		case lower == "@tag.docs.description" && tag != nil:
+			if tag.ExternalDocs == nil {
+				tag.ExternalDocs = &ExternalDocs{}
+			}
+			tag.ExternalDocs.Description = val
+		case lower == "@externaldocs.url":
+			if p.swagger.ExternalDocs == nil {
+				p.swagger.ExternalDocs = &ExternalDocs{}
+			}
+			p.swagger.ExternalDocs.URL = val
+		case lower == "@externaldocs.description":
+			if p.swagger.ExternalDocs == nil {
+				p.swagger.ExternalDocs = &ExternalDocs{}
+			}
+			p.swagger.ExternalDocs.Description = val
+		case strings.HasPrefix(lower, "@securitydefinitions."):
+			def = swaggoSecurityDefinition(ann, strings.TrimPrefix(lower, "@securitydefinitions."))
+			p.swagger.SecurityDefinitions[val] = def
+		case def == nil:
+			// the remaining tags describe a security definition
+		case lower == "@in":
+			def.In = val
+		case lower == "@name":
+			def.Name = val
+		case lower == "@tokenurl":
+			def.TokenUrl = val
+		case lower == "@authorizationurl":
+			def.AuthorizationUrl = val
+		case strings.HasPrefix(lower, "@scope."):
+			if def.Scopes == nil {
+				def.Scopes = make(map[string]string)
+			}
+			def.Scopes[name[len("@scope."):]] = val
+		}
+	}
+}
+
+func swaggoSecurityDefinition(ann *annotation, kind string) *SecurityDefinitions {
+	switch kind {
+	case "basic":
+		return &SecurityDefinitions{Type: "basic"}
+	case "apikey":
+		return &SecurityDefinitions{Type: "apiKey"}
+	case "oauth2.application":
+		return &SecurityDefinitions{Type: "oauth2", Flow: "application"}
+	case "oauth2.implicit":
+		return &SecurityDefinitions{Type: "oauth2", Flow: "implicit"}
+	case "oauth2.password":
+		return &SecurityDefinitions{Type: "oauth2", Flow: "password"}
+	case "oauth2.accesscode":
+		return &SecurityDefinitions{Type: "oauth2", Flow: "accessCode"}
+	}
+
+	panic(ann.errorAt(0, fmt.Sprintf("unsupported security definition type %s", kind)))
+}
+
+func swaggoMimeList(val string) []string {
+	mimes := []string{}
+	for _, mime := range strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if alias, ok := swaggoMimes[mime]; ok {
+			mime = alias
+		}
+		mimes = append(mimes, mime)
+	}
+
+	return mimes
+}
+
+// swaggoRoute is a @Router line, applied once the operation is read.
+type swaggoRoute struct {
+	ann    *annotation
+	path   string
+	method string
+}
+
+// swaggoHeader is a @Header line, applied once every response is known.
+type swaggoHeader struct {
+	codes  []string
+	name   string
+	header *Header
+}
+
+func (p *Parser) parseSwaggoOperation(anns []*annotation) {
+	op := &Operation{}
+	routes := []*swaggoRoute{}
+	headers := []*swaggoHeader{}
+	for _, ann := range anns {
+		val := ann.text
+		switch strings.ToLower(ann.tag) {
+		case "@summary":
+			op.Summary = val
+		case "@description":
+			op.Description = p.description(op.Description, ann)
+		case "@id":
+			op.OperationId = val
+		case "@tags":
+			for _, name := range strings.Split(val, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					op.Tags = append(op.Tags, name)
+				}
+			}
+		case "@accept":
+			op.Consumes = swaggoMimeList(val)
+		case "@produce":
+			op.Produces = swaggoMimeList(val)
+		case "@schemes":
+			op.Schemes = getValueStrings(val)
+		case "@deprecated":
+			op.Deprecated = true
+		case "@security":
+			op.Security = append(op.Security, swaggoSecurity(val)...)
+		case "@param":
+			op.Parameters = append(op.Parameters, p.parseSwaggoParam(ann))
+		case "@success", "@failure", "@response":
+			if op.Responses == nil {
+				op.Responses = make(map[string]*Responses)
+			}
+			p.parseSwaggoResponse(op.Responses, ann)
+		case "@header":
+			headers = append(headers, parseSwaggoHeader(ann))
+		case "@router":
+			fields := swaggoFields(ann)
+			if len(fields) != 2 || !strings.HasPrefix(fields[1], "[") || !strings.HasSuffix(fields[1], "]") {
+				panic(ann.errorAt(0, "expected a path and a [method]"))
+			}
+			routes = append(routes, &swaggoRoute{ann: ann, path: fields[0], method: strings.ToUpper(strings.Trim(fields[1], "[]"))})
+		}
+	}
+
+	for _, h := range headers {
+		for code, resp := range op.Responses {
+			if h.codes[0] != "all" && !hasOption(h.codes, code) {
+				continue
+			}
+			if resp.Headers == nil {
+				resp.Headers = make(map[string]*Header)
+			}
+			resp.Headers[h.name] = h.header
+		}
+	}
+
+	for _, route := range routes {
+		path := p.swagger.Paths[route.path]
+		if path == nil {
+			path = &Path{}
+		}
+		if !setOperation(path, route.method, op) {
+			panic(route.ann.errorAt(route.ann.args()[1].offset, fmt.Sprintf("unsupported method %s", route.method)))
+		}
+		p.swagger.Paths[route.path] = path
+	}
+}
+
+// setOperation sets the operation of path for method and reports whether
+// the method is supported.
+func setOperation(path *Path, method string, op *Operation) bool {
+	switch method {
+	case "GET":
+		path.GET = op
+	case "POST":
+		path.POST = op
+	case "PUT":
+		path.PUT = op
+	case "DELETE":
+		path.DELETE = op
+	case "OPTIONS":
+		path.OPTIONS = op
+	case "HEAD":
+		path.HEAD = op
+	case "PATCH":
+		path.PATCH = op
+	default:
+		return false
+	}
+
+	return true
+}
+
+// swaggoSecurity reads "A", "B[scope1, scope2]", "A && B" (both required)
+// and "A || B" (either one).
+func swaggoSecurity(val string) []map[string][]string {
+	security := []map[string][]string{}
+	for _, alternative := range strings.Split(val, "||") {
+		requirement := map[string][]string{}
+		for _, scheme := range strings.Split(alternative, "&&") {
+			scheme = strings.TrimSpace(scheme)
+			name, scopes := scheme, []string{}
+			if index := strings.Index(scheme, "["); index > 0 && strings.HasSuffix(scheme, "]") {
+				name = strings.TrimSpace(scheme[:index])
+				for _, scope := range strings.Split(scheme[index+1:len(scheme)-1], ",") {
+					if scope = strings.TrimSpace(scope); scope != "" {
+						scopes = append(scopes, scope)
+					}
+				}
+			}
+			if name != "" {
+				requirement[name] = scopes
+			}
+		}
+		security = append(security, requirement)
+	}
+
+	return security
+}
+
+// swaggoFields returns the args of ann as written, without quotes.
+func swaggoFields(ann *annotation) []string {
+	fields := []string{}
+	for _, a := range ann.args() {
+		if a.key != "" {
+			fields = append(fields, a.key+"="+a.value)
+		} else {
+			fields = append(fields, a.value)
+		}
+	}
+
+	return fields
+}
+
+// swaggoAttribute splits an attribute such as enums(a,b) into its name and
+// value.
+func swaggoAttribute(field string) (string, string, bool) {
+	open := strings.Index(field, "(")
+	if open <= 0 || !strings.HasSuffix(field, ")") {
+		return "", "", false
+	}
+
+	return strings.ToLower(field[:open]), field[open+1 : len(field)-1], true
+}
+
+// parseSwaggoParam reads "name in type required [description] [attributes]".
+func (p *Parser) parseSwaggoParam(ann *annotation) *Parameter {
+	args, fields := ann.args(), swaggoFields(ann)
+	if len(fields) < 4 {
+		panic(ann.errorAt(0, "expected name in type required [description] [attributes]"))
+	}
+
+	param := &Parameter{Name: fields[0], In: fields[1]}
+	required, err := strconv.ParseBool(fields[3])
+	if err != nil {
+		panic(ann.errorAt(args[3].offset, fmt.Sprintf("required must be true or false, not %q", fields[3])))
+	}
+	param.Required = required
+
+	if param.In == "body" {
+		param.Schema = p.swaggoSchema(ann, args[2].offset, fields[2])
+	} else if strings.HasPrefix(fields[2], "[]") {
+		param.Type = "array"
+		param.Items = &Items{}
+		param.Items.Type, param.Items.Format = swaggoPrimitive(fields[2][2:])
+	} else {
+		param.Type, param.Format = swaggoPrimitive(fields[2])
+	}
+
+	first := 4
+	if len(fields) > first {
+		if _, _, ok := swaggoAttribute(fields[first]); !ok {
+			param.Description = fields[first]
+			first++
+		}
+	}
+
+	for i := first; i < len(fields); i++ {
+		name, value, ok := swaggoAttribute(fields[i])
+		if !ok {
+			panic(ann.errorAt(args[i].offset, fmt.Sprintf("expected an attribute such as default(...), got %q", fields[i])))
+		}
+
+		switch name {
+		case "default":
+			param.Default = value
+		case "enums":
+			enum := strings.Split(value, ",")
+			if param.Items != nil {
+				param.Items.Enum = enum
+			} else {
+				param.Enum = enum
+			}
+		case "minimum":
+			param.Minimum = swaggoInt(ann, args[i], name, value)
+		case "maximum":
+			param.Maximum = swaggoInt(ann, args[i], name, value)
+		case "minlength":
+			param.MinLength = swaggoInt(ann, args[i], name, value)
+		case "maxlength":
+			param.MaxLength = swaggoInt(ann, args[i], name, value)
+		case "format":
+			param.Format = value
+		case "example":
+			if param.Schema != nil {
+				param.Schema.Example = parseExample(value)
+			} else {
+				param.Example = typedExample(param.Type, value)
+			}
+		default:
+			logrus.Warnf("Ignored @Param %s attribute %s", param.Name, name)
+		}
+	}
+
+	return param
+}
+
+// swaggoInt reads the value of a numeric attribute such as minimum(1). The
+// spec stores these bounds as integers.
+func swaggoInt(ann *annotation, a *arg, name, value string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		panic(ann.errorAt(a.offset, fmt.Sprintf("%s must be an integer, not %q", name, value)))
+	}
+
+	return n
+}
+
+// parseSwaggoResponse reads "code[,code...] [{kind} type] [description]".
+func (p *Parser) parseSwaggoResponse(responses map[string]*Responses, ann *annotation) {
+	args, fields := ann.args(), swaggoFields(ann)
+	if len(fields) == 0 {
+		panic(ann.errorAt(0, "expected code[,code...] [{kind} type] [description]"))
+	}
+
+	var s *Schema
+	rest := fields[1:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "{") {
+		if len(rest) < 2 {
+			panic(ann.errorAt(args[1].offset, fmt.Sprintf("expected a type after %s", rest[0])))
+		}
+		s = p.swaggoSchema(ann, args[2].offset, rest[1])
+		if strings.Trim(rest[0], "{}") == "array" {
+			s = &Schema{Type: "array", Items: s}
+		}
+		rest = rest[2:]
+	}
+
+	for _, code := range strings.Split(fields[0], ",") {
+		resp := &Responses{Schema: s}
+		if len(rest) > 0 {
+			resp.Description = rest[0]
+		} else if status, err := strconv.Atoi(code); err == nil {
+			resp.Description = http.StatusText(status)
+		}
+		responses[code] = resp
+	}
+}
+
+// parseSwaggoHeader reads "code[,code...]|all {type} name [description]".
+func parseSwaggoHeader(ann *annotation) *swaggoHeader {
+	fields := swaggoFields(ann)
+	if len(fields) < 3 {
+		panic(ann.errorAt(0, "expected code[,code...]|all {type} name [description]"))
+	}
+
+	header := &Header{}
+	header.Type, header.Format = swaggoPrimitive(strings.Trim(fields[1], "{}"))
+	if len(fields) > 3 {
+		header.Description = fields[3]
+	}
+
+	return &swaggoHeader{codes: strings.Split(fields[0], ","), name: fields[2], header: header}
+}
+
+// swaggoPrimitive maps swag's and Go's primitive type names to a type and
+// format.
+func swaggoPrimitive(typ string) (string, string) {
+	switch typ {
+	case "integer", "number", "boolean", "file":
+		return typ, ""
+	}
+
+	typ, format, _ := getTypeFormat(typ)
+	return typ, format
+}
+
+// swaggoSchema builds the schema of a type written in a swag annotation:
+// primitives, []T, map[string]T, model.User and compositions such as
+// Response{data=model.User}. Errors are reported at offset of ann.
+func (p *Parser) swaggoSchema(ann *annotation, offset int, typ string) *Schema {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		return &Schema{Type: "array", Items: p.swaggoSchema(ann, offset, typ[2:])}
+	case strings.HasPrefix(typ, "map["):
+		if end := strings.Index(typ, "]"); end > 0 {
+			return &Schema{Type: "object", AdditionalProperties: p.swaggoSchema(ann, offset, typ[end+1:])}
+		}
+	case typ == "interface{}" || typ == "any":
+		return &Schema{}
+	}
+
+	if open := strings.Index(typ, "{"); open > 0 && strings.HasSuffix(typ, "}") {
+		fields := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, field := range splitTopLevel(typ[open+1 : len(typ)-1]) {
+			data := strings.SplitN(field, "=", 2)
+			if len(data) != 2 {
+				panic(ann.errorAt(offset, fmt.Sprintf("expected field=type in the composed type %s", typ)))
+			}
+			fields.Properties[strings.TrimSpace(data[0])] = p.swaggoSchema(ann, offset, strings.TrimSpace(data[1]))
+		}
+		return &Schema{AllOf: []*Schema{p.swaggoSchema(ann, offset, typ[:open]), fields}}
+	}
+
+	switch typ {
+	case "integer", "number", "boolean", "string", "object", "file":
+		return &Schema{Type: typ}
+	}
+	if typ, format, ok := getTypeFormat(typ); ok {
+		return &Schema{Type: typ, Format: format}
+	}
+
+	raw := p.qualifyType(typ)
+	s := &Schema{Ref: "#/definitions/" + definitionKey(raw), RawRefName: raw}
+	p.usedDefinitions = append(p.usedDefinitions, s)
+
+	return s
+}
+
+// splitTopLevel splits s on commas that are not nested in brackets or
+// braces.
+func splitTopLevel(s string) []string {
+	parts := []string{}
+	depth, last := 0, 0
+	for i, char := range s {
+		switch char {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[last:])
+}
+
+// qualifyType turns a type name relative to the annotated file, such as
+// model.User or User, into the full name the model walker looks up.
+func (p *Parser) qualifyType(typ string) string {
+	if base, args, ok := splitGeneric(typ); ok {
+		for i, arg := range args {
+			if _, _, primitive := getTypeFormat(arg); !primitive {
+				args[i] = p.qualifyType(arg)
+			}
+		}
+		return p.qualifyType(base) + "[" + strings.Join(args, ",") + "]"
+	}
+
+	index := strings.LastIndex(typ, ".")
+	if index < 0 {
+		if p.pkgPath == "" {
+			return typ
+		}
+		return p.pkgPath + "." + typ
+	}
+
+	if pkg := typ[:index]; !strings.Contains(pkg, "/") {
+		return importPath(p.file, pkg) + typ[index:]
+	}
+
+	return typ
+}
+
+// filePackage returns the import path of the package f belongs to, or its
+// name when the path cannot be found.
+func (p *Parser) filePackage(f *ast.File) string {
+	dir := filepath.Dir(p.fset.Position(f.Pos()).Filename)
+	if pkg, err := build.ImportDir(dir, build.FindOnly); err == nil && pkg.ImportPath != "" && !strings.HasPrefix(pkg.ImportPath, ".") && !strings.HasPrefix(pkg.ImportPath, "_") {
+		return pkg.ImportPath
+	}
+
+	return f.Name.Name
+}
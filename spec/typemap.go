@@ -0,0 +1,164 @@
+package spec
+
+import (
+	"encoding/json"
+	. "github.com/peak6/arlong/schema"
+	"github.com/peak6/utils/parsetype"
+	"go/ast"
+	"io/ioutil"
+	"strings"
+)
+
+// wellKnownTypes maps library types, keyed by import path and type name,
+// to the schema of their encoding/json output. Types that encode as objects,
+// such as sql.NullString or url.URL, are left to the model walker.
+var wellKnownTypes = map[string]*Schema{
+	"time.Time":                                 {Type: "string", Format: "date-time"},
+	"time.Duration":                             {Type: "integer", Format: "int64"},
+	"encoding/json.RawMessage":                  {},
+	"encoding/json.Number":                      {Type: "number"},
+	"math/big.Int":                              {Type: "integer"},
+	"math/big.Float":                            {Type: "string"},
+	"net.IP":                                    {Type: "string"},
+	"github.com/google/uuid.UUID":               {Type: "string", Format: "uuid"},
+	"github.com/gofrs/uuid.UUID":                {Type: "string", Format: "uuid"},
+	"github.com/satori/go.uuid.UUID":            {Type: "string", Format: "uuid"},
+	"github.com/shopspring/decimal.Decimal":     {Type: "string", Format: "decimal"},
+	"github.com/shopspring/decimal.NullDecimal": {Type: "string", Format: "decimal"},
+}
+
+// LoadTypeMap reads a JSON object that maps Go types to schemas, e.g.
+// {"github.com/org/money.Amount": {"type": "string", "format": "decimal"}}.
+func LoadTypeMap(filename string) (map[string]*Schema, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	typeMap := map[string]*Schema{}
+	if err := json.Unmarshal(b, &typeMap); err != nil {
+		return nil, err
+	}
+
+	return typeMap, nil
+}
+
+// shortTypeName reduces import/path.Type, or its flattened form, to pkg.Type.
+func shortTypeName(raw string) string {
+	segments, typeName := splitTypeName(raw)
+	if len(segments) == 0 {
+		return typeName
+	}
+
+	return segments[len(segments)-1] + "." + typeName
+}
+
+func (p *Parser) parseTypeMap(ann *annotation) {
+	name := ann.word(0)
+	if name == "" || len(ann.args()) < 2 {
+		panic(ann.errorAt(0, "expected a type name and its schema"))
+	}
+
+	def := &Schema{}
+	p.parseDefinitionField(def, ann.values(1))
+	if p.TypeMap == nil {
+		p.TypeMap = make(map[string]*Schema)
+	}
+	p.TypeMap[name] = def
+}
+
+// mappedType returns the schema for a Go type that is not described by its
+// structure: user mappings first, then well known library types and finally
+// types that marshal themselves as text.
+func (p *Parser) mappedType(raw string) *Schema {
+	if raw == "" {
+		return nil
+	}
+
+	short := shortTypeName(raw)
+	for _, name := range []string{raw, fixPath(raw), short} {
+		if def, ok := p.TypeMap[name]; ok {
+			return def
+		}
+	}
+
+	// nested walker names are flattened once visited, recover the import path
+	if model, ok := p.models[fixPath(raw)]; ok && strings.Contains(model.raw, "/") {
+		raw = model.raw
+	}
+
+	for name, def := range wellKnownTypes {
+		if name == raw || fixPath(name) == raw {
+			return def
+		}
+	}
+
+	if strings.Contains(raw, ".") && p.isTextMarshaler(raw) {
+		return &Schema{Type: "string"}
+	}
+
+	return nil
+}
+
+// isTextMarshaler reports whether the named type implements
+// encoding.TextMarshaler without providing its own MarshalJSON.
+func (p *Parser) isTextMarshaler(raw string) bool {
+	_, typeName := splitTypeName(raw)
+	text, custom := false, false
+	for _, f := range p.packageSource(typePackage(raw)) {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); !ok || ident.Name != typeName {
+				continue
+			}
+
+			switch fn.Name.Name {
+			case "MarshalText":
+				text = true
+			case "MarshalJSON":
+				custom = true
+			}
+		}
+	}
+
+	return text && !custom
+}
+
+// applyMappedType fills def from a mapped schema, keeping any description
+// or enum already set from annotations.
+func applyMappedType(def *Schema, mapped *Schema) {
+	def.Type = mapped.Type
+	def.Format = mapped.Format
+	def.Ref = mapped.Ref
+	def.Items = mapped.Items
+	def.AdditionalProperties = mapped.AdditionalProperties
+	def.Properties = mapped.Properties
+	if def.Description == "" {
+		def.Description = mapped.Description
+	}
+	if def.Enum == nil {
+		def.Enum = mapped.Enum
+	}
+}
+
+// walkerTypeName returns the Go type name a walker node stands for.
+func walkerTypeName(pType *parsetype.Type) string {
+	if pType.Type == "ref" && pType.RefType != nil {
+		return pType.RefType.Name
+	}
+
+	switch pType.Type {
+	case "struct", "map", "array":
+		return ""
+	}
+
+	return pType.Type
+}
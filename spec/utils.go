@@ -1,129 +1,30 @@
 package spec
 
 import (
+	"fmt"
+	. "github.com/peak6/arlong/schema"
 	"path"
+	"reflect"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
-func joinString(a, b string) string {
-	return strings.Join([]string{a, b}, " ")
-}
-
-func fixPath(s string) string {
-	return strings.Replace(s, "/", ".", -1)
-}
-
-func findAt(text string) int {
-	size := 0
-	index := 0
-	var char rune
-	for size < len(text) {
-		char, size = utf8.DecodeRuneInString(text)
-		text = text[size:]
-
-		switch char {
-		case ' ', '/':
-			index += size
-			continue
-		case '@':
-			return index
-		default:
-			return -1
-		}
+// joinLines adds b to a as a new line.
+func joinLines(a, b string) string {
+	if a == "" {
+		return b
 	}
 
-	return -1
+	return a + "\n" + b
 }
 
-func getValues(text string) (string, string) {
-	text = strings.Replace(text, "\t", " ", -1)
-	fullText := text
-	size := 0
-	index := 0
-	var char rune
-	for size < len(text) {
-		char, size = utf8.DecodeRuneInString(text)
-		text = text[size:]
-
-		switch char {
-		case ' ':
-			return strings.TrimSpace(fullText[:index]), strings.TrimSpace(fullText[index:])
-		default:
-			index += size
-		}
-	}
-
-	return strings.TrimSpace(fullText), ""
+func fixPath(s string) string {
+	return strings.Replace(s, "/", ".", -1)
 }
 
-func getValueByKey(s string, delim ...rune) map[string]string {
-	if len(delim) == 0 {
-		delim = []rune{'='}
-	}
-
-	result := map[string]string{}
-	fullText := s
-	size := 0
-	index := 0
-	key := ""
-	inQuote := false
-	startVal := 0
-	startKey := 0
-	var char rune
-	for size < len(s) {
-		char, size = utf8.DecodeRuneInString(s)
-		s = s[size:]
-
-		switch char {
-		case ' ', '\t':
-			if inQuote {
-				index += size
-			} else {
-				if key != "" {
-					result[key] = strings.TrimSpace(fullText[startVal:index])
-					key = ""
-					index += size
-					startKey = index
-				} else {
-					if fullText[startKey:index] != "" {
-						result[strings.TrimSpace(fullText[startKey:index])] = ""
-						startKey = index
-					}
-					index += size
-				}
-			}
-		case '"':
-			if !inQuote {
-				inQuote = true
-				index += size
-				startVal = index
-			} else {
-				result[key] = strings.TrimSpace(fullText[startVal:index])
-				inQuote = false
-				key = ""
-				index += size
-				startKey = index
-			}
-		case delim[0]:
-			key = strings.TrimSpace(fullText[startKey:index])
-			index += size
-			result[key] = ""
-			startVal = index
-		default:
-			index += size
-		}
-	}
-
-	if key != "" {
-		if !inQuote {
-			index += size
-		}
-		result[key] = strings.TrimSpace(fullText[startVal:index])
-	}
-
-	return result
+func removeDefinitionRef(s string) string {
+	return strings.TrimPrefix(s, "#/definitions/")
 }
 
 func checkTypePtr(s string) string {
@@ -145,6 +46,10 @@ func getTypeFormat(val string) (string, string, bool) {
 		return "integer", "int32", true
 	case "int64":
 		return "integer", "int64", true
+	case "int8", "int16", "uint8", "uint16", "byte", "rune":
+		return "integer", "int32", true
+	case "uint", "uint32", "uint64":
+		return "integer", "int64", true
 	case "float32", "float64":
 		return "number", "float", true
 	case "bool":
@@ -162,6 +67,42 @@ func getTypeFormat(val string) (string, string, bool) {
 	return val, "", false
 }
 
+// setTypeFormat sets the type and format of s from a type annotation. A
+// format given explicitly with format= is kept.
+func setTypeFormat(s *Schema, val string) {
+	typ, format, _ := getTypeFormat(val)
+	s.Type = typ
+	if s.Format == "" {
+		s.Format = format
+	}
+}
+
+// numberKeys are the options holding an integer constraint.
+var numberKeys = map[string]bool{
+	"maximum":   true,
+	"minimum":   true,
+	"maxLength": true,
+	"minLength": true,
+	"maxItems":  true,
+	"minItems":  true,
+}
+
+func isInt(val string) bool {
+	_, err := strconv.Atoi(val)
+	return err == nil
+}
+
+// checkNumbers reports the integer constraints of ann, such as maximum= or
+// items.minLength=, whose value is not an integer.
+func checkNumbers(ann *annotation) {
+	for _, a := range ann.args() {
+		key := a.key[strings.LastIndex(a.key, ".")+1:]
+		if numberKeys[key] && !isInt(a.value) {
+			panic(ann.errorAt(a.offset, fmt.Sprintf("%s must be an integer, not %q", a.key, a.value)))
+		}
+	}
+}
+
 func strToInt(val string) int {
 	valInt, err := strconv.Atoi(val)
 	if err != nil {
@@ -171,6 +112,23 @@ func strToInt(val string) int {
 	return valInt
 }
 
+func parseSchemaConstraint(s *Schema, key, val string) {
+	switch key {
+	case "maximum":
+		s.Maximum = strToInt(val)
+	case "minimum":
+		s.Minimum = strToInt(val)
+	case "maxLength":
+		s.MaxLength = strToInt(val)
+	case "minLength":
+		s.MinLength = strToInt(val)
+	case "maxItems":
+		s.MaxItems = strToInt(val)
+	case "minItems":
+		s.MinItems = strToInt(val)
+	}
+}
+
 func getValueStrings(s string) []string {
 	fields := strings.Fields(s)
 	for i := 0; i < len(fields); i++ {
@@ -179,16 +137,49 @@ func getValueStrings(s string) []string {
 	return fields
 }
 
-func getValueMapStrings(s string) map[string][]string {
+// requirement reads the args of a @Security annotation as a security
+// requirement: name=scope1,scope2 or a bare name without scopes.
+func requirement(args []*arg) map[string][]string {
 	result := map[string][]string{}
-	data := getValueByKey(s)
-	for key, val := range data {
-		result[key] = strings.Split(val, ",")
+	for _, a := range args {
+		switch {
+		case a.key == "":
+			result[a.value] = []string{}
+		case a.value == "":
+			result[a.key] = []string{}
+		default:
+			result[a.key] = strings.Split(a.value, ",")
+		}
 	}
 
 	return result
 }
 
+// hasRequirement reports whether security already holds req.
+func hasRequirement(security []map[string][]string, req map[string][]string) bool {
+	for _, existing := range security {
+		if reflect.DeepEqual(existing, req) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getExternalDocs(data map[string]string) *ExternalDocs {
+	docs := &ExternalDocs{}
+	for key, val := range data {
+		switch key {
+		case "url":
+			docs.URL = val
+		case "description", "desc":
+			docs.Description = val
+		}
+	}
+
+	return docs
+}
+
 func getMime(s string) string {
 	switch s {
 	case "xml":
@@ -0,0 +1,62 @@
+package spec
+
+import (
+	. "github.com/peak6/arlong/schema"
+)
+
+// walkSchema calls fn for s and every schema nested inside it. References
+// are not followed, so the walk always terminates.
+func walkSchema(s *Schema, fn func(*Schema)) {
+	if s == nil {
+		return
+	}
+
+	fn(s)
+	for _, sub := range s.AllOf {
+		walkSchema(sub, fn)
+	}
+	for _, prop := range s.Properties {
+		walkSchema(prop, fn)
+	}
+	walkSchema(s.Items, fn)
+	walkSchema(s.AdditionalProperties, fn)
+}
+
+func walkOperationSchemas(op *Operation, fn func(*Schema)) {
+	if op == nil {
+		return
+	}
+
+	for _, param := range op.Parameters {
+		walkSchema(param.Schema, fn)
+	}
+	for _, resp := range op.Responses {
+		walkSchema(resp.Schema, fn)
+	}
+}
+
+func walkPathSchemas(path *Path, fn func(*Schema)) {
+	for _, op := range path.Operations() {
+		walkOperationSchemas(op, fn)
+	}
+	for _, param := range path.Parameters {
+		walkSchema(param.Schema, fn)
+	}
+}
+
+// walkSwaggerSchemas calls fn for every schema reachable from the document
+// without following references.
+func walkSwaggerSchemas(swagger *Swagger, fn func(*Schema)) {
+	for _, path := range swagger.Paths {
+		walkPathSchemas(path, fn)
+	}
+	for _, param := range swagger.Parameters {
+		walkSchema(param.Schema, fn)
+	}
+	for _, resp := range swagger.Responses {
+		walkSchema(resp.Schema, fn)
+	}
+	for _, def := range swagger.Definitions {
+		walkSchema(def, fn)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"os"
+)
+
+var unusedCommand = cli.Command{
+	Name:  "unused",
+	Usage: "List definitions, parameters, responses and security definitions no operation uses",
+	Flags: parserFlags,
+	Action: func(c *cli.Context) {
+		parser, err := newParser(c)
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		unused, err := parser.Unused()
+		if err != nil {
+			os.Stderr.WriteString(err.Error())
+			return
+		}
+
+		printSection("definitions", unused.Definitions)
+		printSection("parameters", unused.Parameters)
+		printSection("responses", unused.Responses)
+		printSection("securityDefinitions", unused.SecurityDefinitions)
+
+		if !unused.Empty() {
+			os.Exit(1)
+		}
+	},
+}
+
+func printSection(section string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Printf("%s:\n", section)
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+}